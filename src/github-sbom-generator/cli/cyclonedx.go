@@ -0,0 +1,114 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/uuid"
+)
+
+// cdxFormat selects the CycloneDX serialization writeCycloneDX produces.
+type cdxFormat int
+
+const (
+	cdxFormatJSON cdxFormat = iota
+	cdxFormatXML
+)
+
+// buildCycloneDX renders model as a CycloneDX BOM, the same internal model
+// buildSbom consumes for SPDX so the two formats never drift apart. Unlike
+// SPDX, CycloneDX has no document namespace to set; the BOM's identity is
+// its random SerialNumber.
+func buildCycloneDX(model *sbomModel, creator string) *cdx.BOM {
+	bom := cdx.NewBOM()
+	bom.SerialNumber = fmt.Sprintf("urn:uuid:%s", uuid.New())
+	tools := []cdx.Tool{{Name: creator}}
+	bom.Metadata = &cdx.Metadata{Tools: &tools}
+
+	components := make([]cdx.Component, 0, len(model.components))
+	for _, c := range model.components {
+		comp := cdx.Component{
+			BOMRef:      c.id,
+			Type:        cdx.ComponentTypeLibrary,
+			Name:        c.name,
+			Version:     c.version,
+			PackageURL:  c.purl,
+			Description: c.comment,
+		}
+		if expr := cycloneDXLicenseExpression(c); expr != "" {
+			comp.Licenses = &cdx.Licenses{{Expression: expr}}
+		}
+		var refs []cdx.ExternalReference
+		if c.vcsURL != "" {
+			refs = append(refs, cdx.ExternalReference{Type: cdx.ERTypeVCS, URL: c.vcsURL})
+		}
+		if c.archiveURL != "" {
+			refs = append(refs, cdx.ExternalReference{Type: cdx.ERTypeDistribution, URL: c.archiveURL})
+		}
+		if len(refs) > 0 {
+			comp.ExternalReferences = &refs
+		}
+		components = append(components, comp)
+	}
+	bom.Components = &components
+
+	deps := cycloneDXDependencies(model)
+	if len(deps) > 0 {
+		bom.Dependencies = &deps
+	}
+
+	return bom
+}
+
+// cycloneDXLicenseExpression prefers the concluded license, since it is the
+// more specific of the two once OR/AND synthesis has happened; it falls back
+// to the declared one and finally omits the field for UNKNOWN/NOASSERTION.
+func cycloneDXLicenseExpression(c *component) string {
+	for _, expr := range []string{c.licenseConcluded, c.licenseDeclared} {
+		switch expr {
+		case "", unknownLicenseType, "NOASSERTION", "NONE":
+			continue
+		default:
+			return expr
+		}
+	}
+	return ""
+}
+
+// cycloneDXDependencies groups model's DEPENDS_ON relationships into
+// CycloneDX's per-ref dependency-list shape.
+func cycloneDXDependencies(model *sbomModel) []cdx.Dependency {
+	byRef := make(map[string][]string)
+	var order []string
+	for _, rel := range model.relationships {
+		if rel.kind != "DEPENDS_ON" {
+			continue
+		}
+		if _, ok := byRef[rel.from]; !ok {
+			order = append(order, rel.from)
+		}
+		byRef[rel.from] = append(byRef[rel.from], rel.to)
+	}
+	deps := make([]cdx.Dependency, 0, len(order))
+	for _, ref := range order {
+		dependsOn := byRef[ref]
+		deps = append(deps, cdx.Dependency{Ref: ref, Dependencies: &dependsOn})
+	}
+	return deps
+}
+
+// writeCycloneDX encodes bom to w in the requested format.
+func writeCycloneDX(w io.Writer, bom *cdx.BOM, format cdxFormat) error {
+	var bomFormat cdx.BOMFileFormat
+	switch format {
+	case cdxFormatXML:
+		bomFormat = cdx.BOMFileFormatXML
+	default:
+		bomFormat = cdx.BOMFileFormatJSON
+	}
+	return cdx.NewBOMEncoder(w, bomFormat).Encode(bom)
+}
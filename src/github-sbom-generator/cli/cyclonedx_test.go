@@ -0,0 +1,37 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import "testing"
+
+func TestBuildCycloneDXRendersComponentsAndDependencies(t *testing.T) {
+	model := &sbomModel{
+		components: []*component{
+			{id: "widget", name: "widget", version: "v1.2.3", purl: "pkg:github/acme/widget@v1.2.3", licenseDeclared: "MIT"},
+			{id: "npm-lodash-4.17.21", name: "lodash", version: "4.17.21", purl: "pkg:npm/lodash@4.17.21"},
+		},
+		relationships: []*relationship{
+			{from: "widget", to: "npm-lodash-4.17.21", kind: "DEPENDS_ON"},
+		},
+	}
+
+	bom := buildCycloneDX(model, "test-tool")
+
+	if bom.Components == nil || len(*bom.Components) != len(model.components) {
+		t.Fatalf("buildCycloneDX: got %v components, want %d", bom.Components, len(model.components))
+	}
+	for _, c := range *bom.Components {
+		if c.BOMRef == "widget" && (c.Licenses == nil || len(*c.Licenses) != 1 || (*c.Licenses)[0].Expression != "MIT") {
+			t.Errorf("buildCycloneDX: widget component license = %+v, want MIT", c.Licenses)
+		}
+	}
+
+	if bom.Dependencies == nil || len(*bom.Dependencies) != 1 {
+		t.Fatalf("buildCycloneDX: got %v dependencies, want 1 entry", bom.Dependencies)
+	}
+	dep := (*bom.Dependencies)[0]
+	if dep.Ref != "widget" || dep.Dependencies == nil || len(*dep.Dependencies) != 1 || (*dep.Dependencies)[0] != "npm-lodash-4.17.21" {
+		t.Errorf("buildCycloneDX: dependency entry = %+v, want Ref=widget Dependencies=[npm-lodash-4.17.21]", dep)
+	}
+}
@@ -4,56 +4,54 @@
 package cli
 
 import (
-	"archive/tar"
-	"bytes"
-	"compress/gzip"
 	"fmt"
-	"io"
 	"io/fs"
-	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
-	"regexp"
+	"runtime"
 	"strings"
-	"time"
 
 	git "github.com/go-git/go-git/v5"
-	"github.com/google/licensecheck"
-	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
 	spdxjson "github.com/spdx/tools-golang/json"
-	spdxcommon "github.com/spdx/tools-golang/spdx/v2/common"
-	spdx "github.com/spdx/tools-golang/spdx/v2/v2_3"
 	spdxtv "github.com/spdx/tools-golang/tagvalue"
 	"github.com/spf13/cobra"
-)
 
-const (
-	defaultNamespace   = "https://github.com/lf-edge/eve/spdx"
-	creator            = "https://github.com/lf-edge/eve/tools/github-sbom-generator"
-	coverageThreshold  = 75
-	unknownLicenseType = "UNKNOWN"
+	"github.com/lf-edge/eve/tools/github-sbom-generator/fetch"
 )
 
-var (
-	githubDownloadRegex = regexp.MustCompile(`tarball/([^\/]+)$`)
+const (
+	defaultNamespace         = "https://github.com/lf-edge/eve/spdx"
+	creator                  = "https://github.com/lf-edge/eve/tools/github-sbom-generator"
+	defaultCoverageThreshold = 75
+	unknownLicenseType       = "UNKNOWN"
 )
 
 func generateCmd() *cobra.Command {
 	var (
-		outputFormat string
-		namespace    string
+		outputFormat     string
+		namespace        string
+		githubToken      string
+		gitlabToken      string
+		bitbucketAuth    string
+		sshKey           string
+		useNetrc         bool
+		useGitCredHelper bool
+		withTransitive   bool
+		concurrency      int
+		licenseCoverage  int
 	)
 	cmd := &cobra.Command{
 		Use:   "generate",
 		Short: "Generate SBoM for GitHub repos as a single unit",
 		Long: `Generate SBOMs for one or more github repos as a single unit.
-		Can generate for multiple at once. Output can be in spdx or spdx-json formats.
+		Can generate for multiple at once. Output can be in spdx, spdx-json, cyclonedx-json or cyclonedx-xml formats.
 
 		URL to the repo should be in the form of <scheme>://<host>/<path>#<ref>. See examples.
 		<ref> can be either a tag or a commit hash.
 
+		Non-GitHub sources are also accepted: gitlab.com and bitbucket.org repos, a
+		"git::<url>" forced clone of any other git host, or a file:// / bare path to
+		a local checkout.
+
 		Alternatively, if the URL is a file path, it will assume the represented path
 		is a cloned git repo, and will take the remote 'origin' repo and current checked out
 		commit as the URL and ref. Files *must* be either absolute paths, beginning with '/', or relative
@@ -62,42 +60,55 @@ func generateCmd() *cobra.Command {
 		Example: `github-sbom-generator generate https://github.com/foo/bar#v1.2.3 https://github.com/foo/bar#abcd1122 ./path/to/repo`,
 		Args:    cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var allRepos []*repoWithReader
-			for _, repo := range args {
-				log.Debugf("Processing %s", repo)
-				r, err := parse(repo)
-				if err != nil {
-					log.Fatalf("Error generating %s: %v", repo, err)
-				}
-				allRepos = append(allRepos, r)
+			creds := fetch.NewCredentialsFromEnv(githubToken, gitlabToken, bitbucketAuth, sshKey, useNetrc, useGitCredHelper)
+			cache, err := fetch.NewCache()
+			if err != nil {
+				return fmt.Errorf("opening fetch cache: %v", err)
+			}
+			allRepos, err := parseAll(args, concurrency, creds, cache)
+			if err != nil {
+				return err
+			}
+			model, err := buildModel(allRepos, withTransitive, licenseCoverage)
+			if err != nil {
+				return err
 			}
 			switch outputFormat {
 			case "spdx":
-				sbom, err := buildSbom(allRepos, namespace, creator)
-				if err != nil {
-					return err
-				}
-				return spdxtv.Write(sbom, os.Stdout)
+				return spdxtv.Write(buildSbom(model, namespace, creator), os.Stdout)
 			case "spdx-json":
-				sbom, err := buildSbom(allRepos, namespace, creator)
-				if err != nil {
-					return err
-				}
-				return spdxjson.Write(sbom, os.Stdout)
+				return spdxjson.Write(buildSbom(model, namespace, creator), os.Stdout)
+			case "cyclonedx-json":
+				return writeCycloneDX(os.Stdout, buildCycloneDX(model, creator), cdxFormatJSON)
+			case "cyclonedx-xml":
+				return writeCycloneDX(os.Stdout, buildCycloneDX(model, creator), cdxFormatXML)
 			default:
 				return fmt.Errorf("unknown output format %s", outputFormat)
 			}
 		},
 	}
 
-	cmd.Flags().StringVar(&outputFormat, "format", "list", "Output format: list, spdx, spdx-json")
+	cmd.Flags().StringVar(&outputFormat, "format", "list", "Output format: list, spdx, spdx-json, cyclonedx-json, cyclonedx-xml")
 	cmd.Flags().StringVar(&namespace, "namespace", defaultNamespace, "document namespace to use for spdx output formats, will have a UUID appended")
+	cmd.Flags().StringVar(&githubToken, "github-token", "", "token for authenticating to private GitHub repos, falls back to GITHUB_TOKEN")
+	cmd.Flags().StringVar(&gitlabToken, "gitlab-token", "", "token for authenticating to private GitLab repos, falls back to GITLAB_TOKEN")
+	cmd.Flags().StringVar(&bitbucketAuth, "bitbucket-auth", "", "username:app-password for authenticating to private Bitbucket repos")
+	cmd.Flags().StringVar(&sshKey, "ssh-key", "", "path to an SSH private key to use for git:: and ssh:// sources, falls back to the SSH agent")
+	cmd.Flags().BoolVar(&useNetrc, "netrc", false, "resolve credentials from ~/.netrc (or $NETRC) when no token flag/env applies")
+	cmd.Flags().BoolVar(&useGitCredHelper, "git-credential-helper", false, "fall back to `git credential fill` (and whatever credential.helper it resolves) when no token flag/env/netrc applies")
+	cmd.Flags().BoolVar(&withTransitive, "with-transitive", false, "parse language manifests (go.mod, package-lock.json, Cargo.lock, requirements.txt, Pipfile.lock, pom.xml) and emit a package + DEPENDS_ON relationship per transitive dependency")
+	cmd.Flags().IntVar(&concurrency, "concurrency", runtime.NumCPU(), "number of repos to fetch in parallel")
+	cmd.Flags().IntVar(&licenseCoverage, "license-coverage", defaultCoverageThreshold, "minimum licensecheck match percentage required to trust a license file's detected identifier")
 
 	return cmd
 }
 
 type repoWithReader struct {
-	url *url.URL
+	source *fetch.Source
+	// release is the nearest-tag enrichment resolved for source, or nil
+	// when none could be found (no tags in the history, or a forge we
+	// can't query the way we'd need to).
+	release *fetch.Release
 	fs.FS
 	close func() error
 }
@@ -109,286 +120,76 @@ func (r *repoWithReader) Close() error {
 	return nil
 }
 
-func parse(repoWithRef string) (r *repoWithReader, err error) {
-	var (
-		repo      = repoWithRef
-		readerDir string
-		closer    func() error
-	)
-	// first check to see if it is a file path
+func parse(repoWithRef string, creds *fetch.Credentials, cache *fetch.Cache) (r *repoWithReader, err error) {
+	// first check to see if it is a file path to a local git checkout
 	if strings.HasPrefix(repoWithRef, "/") || strings.HasPrefix(repoWithRef, ".") {
-		// it is a file path, so we need to get the remote origin
-		// and current commit
-		// eventually, should add check for tags, but that is for the future
-		r, err := git.PlainOpen(repoWithRef)
-		if err != nil {
-			return nil, fmt.Errorf("unable to open repo at %s: %v", repoWithRef, err)
-		}
-		remote, err := r.Remote("origin")
-		if err != nil {
-			return nil, fmt.Errorf("unable to get remote origin for repo at %s: %v", repoWithRef, err)
-		}
-		config := remote.Config()
-		if len(config.URLs) == 0 {
-			return nil, fmt.Errorf("no remote origin for repo at %s", repoWithRef)
-		}
-		// we only support one URL
-		repo = config.URLs[0]
-
-		// it might be a git@github.com: URL, so replace it
-		repo = strings.Replace(repo, "git@github.com:", "https://github.com/", 1)
-
-		// add the most recent commit to it
-		commit, err := r.Head()
-		if err != nil {
-			return nil, fmt.Errorf("unable to get HEAD for repo at %s: %v", repoWithRef, err)
-		}
-		repo = fmt.Sprintf("%s#%s", repo, commit.Hash())
-		readerDir = repoWithRef
-	} else {
-		// tmpdir to save our files
-		tmpDir, err := os.MkdirTemp("", "sbom")
-		if err != nil {
-			return nil, err
-		}
+		return parseLocalRepo(repoWithRef)
+	}
 
-		// git protocol means clone the whole thing
-		// it is a tgz file, so we should be able to scan it
-		var gz *gzip.Reader
-		err = extractURLToPath(repoWithRef, tmpDir, func(r io.Reader) (io.Reader, error) {
-			gz, err = gzip.NewReader(r)
-			return gz, err
-		})
+	s, err := fetch.Detect(repoWithRef)
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect source type for %s: %v", repoWithRef, err)
+	}
+	if s.Getter == "file" {
+		rel, err := fetch.DescribeRelease(s.URL, s, creds)
 		if err != nil {
-			return nil, err
-		}
-		// directory contains everything, so go look for files
-		readerDir = tmpDir
-		closer = func() error {
-			if err := gz.Close(); err != nil {
-				return err
-			}
-			if err := os.RemoveAll(tmpDir); err != nil {
-				return err
-			}
-			return nil
+			return nil, fmt.Errorf("resolving release for %s: %v", repoWithRef, err)
 		}
+		return &repoWithReader{FS: os.DirFS(s.URL), source: s, release: rel}, nil
 	}
 
-	// get repo and ref
-	parsed, err := url.Parse(repo)
+	dir, release, err := cache.Get(s, creds)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse url %s: %v", repoWithRef, err)
-	}
-	if parsed.Scheme == "" || parsed.Host == "" || parsed.Path == "" {
-		return nil, fmt.Errorf("url %s is not valid", repoWithRef)
+		return nil, fmt.Errorf("unable to fetch %s: %v", repoWithRef, err)
 	}
-	r = &repoWithReader{
-		FS:    os.DirFS(readerDir),
-		url:   parsed,
-		close: closer,
-	}
-
-	return r, nil
-}
-
-func buildSbom(repos []*repoWithReader, namespace, creator string) (*spdx.Document, error) {
-	var packages []*spdx.Package
-	for _, r := range repos {
-		// what do we want to add?
-		// - PackageLicenseConcluded
-		// - PackageLicenseDeclared
-		// - PackageCopyrightText
-		u := r.url
-		downloadURL := githubUrlToDownload(u)
-		// we have some logic about versions
-		name := filepath.Base(u.Path)
-		pkg := &spdx.Package{
-			PackageName:             name,
-			PackageSPDXIdentifier:   spdxcommon.MakeDocElementID("Package", name).ElementRefID,
-			PackageDownloadLocation: downloadURL,
-			PackageLicenseConcluded: "NOASSERTION",
-			PackageLicenseDeclared:  "NONE",
-			PackageExternalReferences: []*spdx.PackageExternalReference{
-				{Category: "PACKAGE-MANAGER", RefType: "purl", Locator: fmt.Sprintf("pkg:generic/git?download_url=%s", u.String())},
-			},
-		}
-		version := u.Fragment
-		if version != "" {
-			pkg.PackageVersion = version
-		}
-		licenseDeclared, licenseConcluded := getLicenseFromReader(r)
-		if licenseDeclared != "" {
-			pkg.PackageLicenseDeclared = licenseDeclared
-		}
-		if licenseConcluded != "" {
-			pkg.PackageLicenseConcluded = licenseConcluded
-		}
-
-		// could we get a version from the URL?
-		if (u.Scheme == "git" || strings.HasSuffix(name, ".git")) && u.Fragment != "" {
-			pkg.PackageVersion = u.Fragment
-		}
-
-		packages = append(packages, pkg)
+	rel, err := fetch.DescribeRelease(dir, s, creds)
+	if err != nil {
+		return nil, fmt.Errorf("resolving release for %s: %v", repoWithRef, err)
 	}
-	return &spdx.Document{
-		SPDXVersion:       "SPDX-2.3",
-		DataLicense:       "CC0-1.0",
-		SPDXIdentifier:    "DOCUMENT",
-		DocumentName:      "github-repo",
-		DocumentNamespace: fmt.Sprintf("%s-%s", namespace, uuid.New()),
 
-		CreationInfo: &spdx.CreationInfo{
-			Created: time.Now().UTC().Format(time.RFC3339),
-			Creators: []spdxcommon.Creator{
-				{Creator: creator, CreatorType: "Tool"},
-			},
-		},
-		Packages: packages,
+	return &repoWithReader{
+		FS:      os.DirFS(dir),
+		source:  s,
+		release: rel,
+		close:   func() error { release(); return nil },
 	}, nil
 }
 
-// getLicenseFromReader try to determine license from the reader
-func getLicenseFromReader(fsys *repoWithReader) (string, string) {
-	if fsys == nil {
-		return "", ""
-	}
-	defer fsys.Close()
-
-	// directory contains everything, so go look for files
-	var licenses []string
-	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		// ignore git directory
-		if path == ".git" || strings.HasPrefix(path, ".git/") {
-			return nil
-		}
-		switch {
-		case d.IsDir():
-			return nil
-		case d.Type() == fs.ModeSymlink:
-			// ignore them
-			return nil
-		default:
-			// make sure it is not vendored
-			filename := filepath.Base(path)
-			// ignore any that are not a known filetype
-			if _, ok := licenseFileNames[filename]; !ok {
-				return nil
-			}
-			parts := strings.Split(filepath.Dir(path), string(filepath.Separator))
-			for _, part := range parts {
-				if part == "vendor" {
-					return nil
-				}
-			}
-			// it is a file wioth the right name not in a vendor path
-			r, err := fsys.Open(path)
-			if err != nil {
-				return err
-			}
-			defer r.Close()
-			var buf bytes.Buffer
-			if _, err := io.Copy(&buf, r); err != nil {
-				return err
-			}
-			cov := licensecheck.Scan(buf.Bytes())
-
-			if cov.Percent < float64(coverageThreshold) {
-				licenses = append(licenses, unknownLicenseType)
-			}
-			for _, m := range cov.Match {
-				licenses = append(licenses, m.ID)
-			}
-			return nil
-		}
-	})
+// parseLocalRepo treats path as an existing git checkout: the SBOM reflects
+// its "origin" remote and currently checked-out commit, but files are read
+// straight out of the working tree rather than being fetched anew.
+func parseLocalRepo(path string) (*repoWithReader, error) {
+	repo, err := git.PlainOpen(path)
 	if err != nil {
-		return "", ""
-	}
-	if len(licenses) == 0 {
-		return "", ""
+		return nil, fmt.Errorf("unable to open repo at %s: %v", path, err)
 	}
-	// declared is all of them, but made unique
-	var (
-		uniqueLicenses []string
-		m              = make(map[string]bool)
-	)
-	for _, l := range licenses {
-		if _, ok := m[l]; !ok {
-			m[l] = true
-			uniqueLicenses = append(uniqueLicenses, l)
-		}
-	}
-
-	licensesDeclared := strings.Join(uniqueLicenses, " AND ")
-	// concluded is the most relevant. Somewhat arbitrarily, we take the first that is not unknown
-	var licenseConcluded string
-	for _, l := range uniqueLicenses {
-		if l != unknownLicenseType {
-			licenseConcluded = l
-			break
-		}
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get remote origin for repo at %s: %v", path, err)
 	}
-	if licenseConcluded == "" {
-		licenseConcluded = unknownLicenseType
+	config := remote.Config()
+	if len(config.URLs) == 0 {
+		return nil, fmt.Errorf("no remote origin for repo at %s", path)
 	}
-	return licensesDeclared, licenseConcluded
-}
-
-type decompress func(io.Reader) (io.Reader, error)
+	// we only support one URL
+	origin := config.URLs[0]
+	// it might be a git@github.com: URL, so replace it
+	origin = strings.Replace(origin, "git@github.com:", "https://github.com/", 1)
 
-func extractURLToPath(u string, path string, decompress decompress) error {
-	// it is a tgz file, so we should be able to scan it
-	res, err := http.Get(u)
+	commit, err := repo.Head()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("unable to get HEAD for repo at %s: %v", path, err)
 	}
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return nil
-	}
-	// gunzip and untar the file
-	dr, err := decompress(res.Body)
+
+	s, err := fetch.Detect(fmt.Sprintf("%s#%s", origin, commit.Hash()))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("unable to detect source type for origin %s of %s: %v", origin, path, err)
 	}
-	tr := tar.NewReader(dr)
-	for {
-		header, err := tr.Next()
 
-		if err == io.EOF {
-			break
-		}
-
-		if err != nil {
-			return err
-		}
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			if err := os.Mkdir(filepath.Join(path, header.Name), 0755); err != nil {
-				log.Fatalf("extract: Mkdir() failed: %s", err.Error())
-			}
-		case tar.TypeReg:
-			outFile, err := os.Create(filepath.Join(path, header.Name))
-			if err != nil {
-				log.Fatalf("extract: Create() failed: %s", err.Error())
-			}
-			if _, err := io.Copy(outFile, tr); err != nil {
-				log.Fatalf("extract: Copy() failed: %s", err.Error())
-			}
-			outFile.Close()
-		}
+	rel, err := fetch.DescribeRelease(path, s, nil)
+	if err != nil {
+		return nil, fmt.Errorf("resolving release for repo at %s: %v", path, err)
 	}
-	return nil
-}
 
-func githubUrlToDownload(u *url.URL) string {
-	// remove '.git'  from path, as that does not work for the github archive URL
-	u.Path = strings.TrimSuffix(u.Path, ".git")
-	return fmt.Sprintf("%s://%s%s/archive/%s.tar.gz", u.Scheme, u.Host, u.Path, u.Fragment)
+	return &repoWithReader{FS: os.DirFS(path), source: s, release: rel}, nil
 }
@@ -0,0 +1,318 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/licensecheck"
+)
+
+// licenseFileNames are the file basenames scanLicenses treats as license
+// text, keyed for an O(1) membership check while walking.
+var licenseFileNames = map[string]bool{
+	"LICENSE":        true,
+	"LICENSE.txt":    true,
+	"LICENSE.md":     true,
+	"LICENSE-MIT":    true,
+	"LICENSE-APACHE": true,
+	"LICENCE":        true,
+	"COPYING":        true,
+	"COPYING.LESSER": true,
+	"COPYRIGHT":      true,
+	"UNLICENSE":      true,
+}
+
+// alternativeLicenseNames are basenames whose presence alongside a sibling
+// from this same set signals "pick one" (e.g. LICENSE-MIT + LICENSE-APACHE),
+// rather than "both apply".
+var alternativeLicenseNames = map[string]bool{
+	"LICENSE-MIT":    true,
+	"LICENSE-APACHE": true,
+	"COPYING.LESSER": true,
+	"COPYING":        true,
+}
+
+// sourceFileExtensions bounds the SPDX-License-Identifier header scan to
+// files we can cheaply recognize as source rather than binary/vendored data.
+var sourceFileExtensions = map[string]bool{
+	".go": true, ".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true,
+	".rs": true, ".py": true, ".js": true, ".ts": true, ".java": true, ".rb": true,
+	".sh": true, ".proto": true,
+}
+
+// maxSPDXHeaderScanSize bounds how much of a source file scanLicenses reads
+// looking for an SPDX-License-Identifier header; the tag is always near the
+// top, so there's no need to read the whole file.
+const maxSPDXHeaderScanSize = 4096
+
+// spdxIdentifierRegex captures the whole expression after the tag, to the
+// end of the line, since compound expressions ("Apache-2.0 WITH
+// LLVM-exception", "(MIT OR Apache-2.0)") contain whitespace themselves; any
+// trailing comment-close marker on the same line is stripped afterward.
+var spdxIdentifierRegex = regexp.MustCompile(`SPDX-License-Identifier:\s*(.+)`)
+
+// commentCloseMarkers are trailing tokens that can follow the expression on
+// the same line when it's embedded in a block or HTML comment.
+var commentCloseMarkers = []string{"*/", "-->", "#>"}
+
+// cleanSPDXExpression trims a raw regex capture down to the bare license
+// expression, stripping a same-line trailing comment terminator.
+func cleanSPDXExpression(raw string) string {
+	expr := strings.TrimSpace(raw)
+	for _, marker := range commentCloseMarkers {
+		if idx := strings.Index(expr, marker); idx >= 0 {
+			expr = expr[:idx]
+		}
+	}
+	return strings.TrimSpace(expr)
+}
+
+// licenseHit is one license file scanLicenses found, with enough context
+// (its basename) to decide whether it's an alternative to its siblings.
+type licenseHit struct {
+	filename string
+	license  string
+}
+
+// subtreeLicense is a nested directory whose license should be surfaced as
+// its own SPDX package, related to the repo root via CONTAINS.
+type subtreeLicense struct {
+	path    string
+	license string
+}
+
+// licenseScanResult is everything scanLicenses learned about a repo's
+// licensing: the root expression pair, plus any nested subtrees that carry
+// their own license.
+type licenseScanResult struct {
+	declared  string
+	concluded string
+	subtrees  []subtreeLicense
+}
+
+// scanLicenses walks fsys looking for license files and per-file
+// SPDX-License-Identifier headers, synthesizing an SPDX license expression:
+// alternative license files (LICENSE-MIT + LICENSE-APACHE, COPYING +
+// COPYING.LESSER) become "A OR B"; anything else found together in the same
+// directory becomes "A AND B". Nested directories with their own license
+// file(s) are reported as subtrees rather than merged into the root.
+// coverageThreshold overrides the minimum licensecheck match percentage
+// required to trust a license file's detected identifier.
+func scanLicenses(fsys fs.FS, coverageThreshold int) (licenseScanResult, error) {
+	byDir := make(map[string][]licenseHit)
+	var dirOrder []string
+	var spdxHeaders []string
+
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == ".git" || strings.HasPrefix(path, ".git/") {
+			return nil
+		}
+		if d.IsDir() || d.Type() == fs.ModeSymlink {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		for _, part := range strings.Split(dir, string(filepath.Separator)) {
+			if part == "vendor" {
+				return nil
+			}
+		}
+		filename := filepath.Base(path)
+		switch {
+		case licenseFileNames[filename]:
+			lic, err := detectLicense(fsys, path, coverageThreshold)
+			if err != nil {
+				return err
+			}
+			if _, ok := byDir[dir]; !ok {
+				dirOrder = append(dirOrder, dir)
+			}
+			byDir[dir] = append(byDir[dir], licenseHit{filename: filename, license: lic})
+		case sourceFileExtensions[filepath.Ext(filename)]:
+			if id, ok := spdxHeaderFor(fsys, path); ok {
+				spdxHeaders = append(spdxHeaders, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return licenseScanResult{}, err
+	}
+
+	var result licenseScanResult
+	for _, dir := range dirOrder {
+		hits := byDir[dir]
+		expr := combineDirLicenses(hits)
+		if dir == "." {
+			result.declared = expr
+			result.concluded = firstKnownLicense(hits)
+			continue
+		}
+		result.subtrees = append(result.subtrees, subtreeLicense{path: dir, license: expr})
+	}
+
+	result.concluded = mergeSPDXHeaders(result.concluded, spdxHeaders)
+	return result, nil
+}
+
+// combineDirLicenses joins the license identifiers found in one directory:
+// "OR" when every file in it is one of alternativeLicenseNames (so the
+// reader is meant to pick one), "AND" otherwise.
+func combineDirLicenses(hits []licenseHit) string {
+	allAlternatives := len(hits) > 1
+	seen := make(map[string]bool)
+	var unique []string
+	for _, h := range hits {
+		if !alternativeLicenseNames[h.filename] {
+			allAlternatives = false
+		}
+		if h.license == "" || seen[h.license] {
+			continue
+		}
+		seen[h.license] = true
+		unique = append(unique, h.license)
+	}
+	sort.Strings(unique)
+	switch len(unique) {
+	case 0:
+		return ""
+	case 1:
+		return unique[0]
+	default:
+		joiner := " AND "
+		if allAlternatives {
+			joiner = " OR "
+		}
+		return strings.Join(unique, joiner)
+	}
+}
+
+// firstKnownLicense picks the most relevant single identifier to report as
+// the concluded license: the first detected one that isn't UNKNOWN, falling
+// back to UNKNOWN if that's all there is.
+func firstKnownLicense(hits []licenseHit) string {
+	for _, h := range hits {
+		if h.license != unknownLicenseType {
+			return h.license
+		}
+	}
+	if len(hits) == 0 {
+		return ""
+	}
+	return unknownLicenseType
+}
+
+// detectLicense runs licensecheck against path's contents, returning
+// UNKNOWN when the best match covers less of the file than
+// coverageThreshold percent.
+func detectLicense(fsys fs.FS, path string, coverageThreshold int) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, f); err != nil {
+		return "", err
+	}
+	cov := licensecheck.Scan(buf.Bytes())
+	if cov.Percent < float64(coverageThreshold) || len(cov.Match) == 0 {
+		return unknownLicenseType, nil
+	}
+	return cov.Match[0].ID, nil
+}
+
+// spdxHeaderFor reads up to maxSPDXHeaderScanSize bytes of path looking for
+// an "SPDX-License-Identifier:" header.
+func spdxHeaderFor(fsys fs.FS, path string) (string, bool) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	buf := make([]byte, maxSPDXHeaderScanSize)
+	n, _ := io.ReadFull(f, buf)
+	m := spdxIdentifierRegex.FindSubmatch(buf[:n])
+	if m == nil {
+		return "", false
+	}
+	expr := cleanSPDXExpression(string(m[1]))
+	if expr == "" {
+		return "", false
+	}
+	return expr, true
+}
+
+// mergeSPDXHeaders folds the per-file SPDX-License-Identifier headers found
+// across the tree into the concluded expression: each distinct identifier
+// not already present is ANDed in, since a file asserting its own license
+// alongside a repo-level LICENSE is additional, not alternative, licensing.
+// Any operand that is itself a compound "OR" expression is
+// parenthesized first, since the SPDX license-expression grammar requires
+// explicit grouping when AND and OR are mixed.
+func mergeSPDXHeaders(concluded string, headers []string) string {
+	unique := dedupe(headers)
+	if len(unique) == 0 {
+		return concluded
+	}
+	sort.Strings(unique)
+
+	var parts []string
+	if concluded != "" && concluded != unknownLicenseType {
+		parts = append(parts, concluded)
+	}
+	for _, h := range unique {
+		if h == concluded {
+			continue
+		}
+		parts = append(parts, h)
+	}
+	switch len(parts) {
+	case 0:
+		return concluded
+	case 1:
+		return parts[0]
+	default:
+		for i, p := range parts {
+			parts[i] = parenthesizeIfCompound(p)
+		}
+		return strings.Join(parts, " AND ")
+	}
+}
+
+// parenthesizeIfCompound wraps expr in parentheses if it contains an "OR"
+// operator and isn't already parenthesized, so joining it with "AND" into a
+// larger expression stays unambiguous per the SPDX license-expression
+// grammar.
+func parenthesizeIfCompound(expr string) string {
+	if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		return expr
+	}
+	if strings.Contains(expr, " OR ") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
+
+func dedupe(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	var out []string
+	for _, s := range in {
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestSpdxHeaderFor(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"single", "// SPDX-License-Identifier: Apache-2.0\n", "Apache-2.0"},
+		{"with-exception", "// SPDX-License-Identifier: Apache-2.0 WITH LLVM-exception\n", "Apache-2.0 WITH LLVM-exception"},
+		{"parenthesized-or", "/* SPDX-License-Identifier: (MIT OR Apache-2.0) */\n", "(MIT OR Apache-2.0)"},
+		{"html-comment", "<!-- SPDX-License-Identifier: MIT -->\n", "MIT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"f.go": {Data: []byte(tt.data)}}
+			got, ok := spdxHeaderFor(fsys, "f.go")
+			if !ok {
+				t.Fatalf("spdxHeaderFor: expected a match for %q", tt.data)
+			}
+			if got != tt.want {
+				t.Errorf("spdxHeaderFor(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeSPDXHeadersParenthesizesMixedOperators(t *testing.T) {
+	got := mergeSPDXHeaders("MIT OR Apache-2.0", []string{"BSD-3-Clause"})
+	want := "(MIT OR Apache-2.0) AND BSD-3-Clause"
+	if got != want {
+		t.Errorf("mergeSPDXHeaders = %q, want %q", got, want)
+	}
+}
+
+func TestMergeSPDXHeadersSingleHeaderNoConcluded(t *testing.T) {
+	got := mergeSPDXHeaders(unknownLicenseType, []string{"MIT OR Apache-2.0"})
+	want := "MIT OR Apache-2.0"
+	if got != want {
+		t.Errorf("mergeSPDXHeaders = %q, want %q (no parens needed for a lone operand)", got, want)
+	}
+}
+
+func TestMergeSPDXHeadersDedupesAgainstConcluded(t *testing.T) {
+	got := mergeSPDXHeaders("MIT", []string{"MIT"})
+	if got != "MIT" {
+		t.Errorf("mergeSPDXHeaders = %q, want %q", got, "MIT")
+	}
+}
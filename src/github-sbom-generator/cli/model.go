@@ -0,0 +1,166 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/lf-edge/eve/tools/github-sbom-generator/fetch"
+	"github.com/lf-edge/eve/tools/github-sbom-generator/manifest"
+)
+
+// component is the ecosystem-agnostic shape of one SBOM entry. Building it
+// once, up front, lets buildSbom and buildCycloneDX share all of the actual
+// repo walking (fetching, license scanning, manifest parsing) instead of
+// duplicating it per output format.
+type component struct {
+	id               string // stable, document-local identifier
+	name             string
+	version          string
+	purl             string
+	downloadLocation string
+	vcsURL           string
+	archiveURL       string
+	licenseDeclared  string
+	licenseConcluded string
+	comment          string
+	releaseDate      string // RFC3339, from the resolved release tag/commit
+	supplierName     string // tagger (or committer) identity behind releaseDate
+	supplierEmail    string
+}
+
+// relationship records that "from" depends on or contains "to", identified
+// by their component.id.
+type relationship struct {
+	from, to string
+	kind     string // "DEPENDS_ON" or "CONTAINS"
+}
+
+// sbomModel is the internal representation both output builders render from.
+type sbomModel struct {
+	components    []*component
+	relationships []*relationship
+}
+
+// buildModel walks repos once, resolving each to a root component plus,
+// when withTransitive is set, its manifest-declared dependencies and a
+// placeholder for any vendored code tree. The root's version, release date,
+// and supplier are refined from the repo's resolved release, when one could
+// be found, in preference to the raw ref the user asked for.
+func buildModel(repos []*repoWithReader, withTransitive bool, coverageThreshold int) (*sbomModel, error) {
+	model := &sbomModel{}
+	for _, r := range repos {
+		defer r.Close()
+		s := r.source
+		name := filepath.Base(s.Path)
+		if name == "" || name == "." {
+			name = filepath.Base(s.URL)
+		}
+		archiveURL := s.URL
+		if s.Getter == "http" {
+			archiveURL = fetch.ArchiveURL(s)
+		}
+		root := &component{
+			id:               name,
+			name:             name,
+			version:          s.Ref,
+			purl:             s.PackageURL(),
+			downloadLocation: s.DownloadLocation(),
+			vcsURL:           s.URL,
+			archiveURL:       archiveURL,
+		}
+		if rel := r.release; rel != nil {
+			root.version = rel.Version
+			root.releaseDate = rel.Date.UTC().Format(time.RFC3339)
+			root.supplierName = rel.AuthorName
+			root.supplierEmail = rel.AuthorEmail
+		}
+		model.components = append(model.components, root)
+
+		if withTransitive {
+			deps, err := manifest.ParseAll(r.FS)
+			if err != nil {
+				return nil, fmt.Errorf("parsing dependency manifests for %s: %v", name, err)
+			}
+			for _, d := range deps {
+				depID := dependencyComponentID(d)
+				model.components = append(model.components, &component{
+					id:      depID,
+					name:    d.Name,
+					version: d.Version,
+					purl:    d.PURL,
+				})
+				model.relationships = append(model.relationships, &relationship{
+					from: root.id,
+					to:   depID,
+					kind: "DEPENDS_ON",
+				})
+			}
+			if hasVendorDir(r.FS) {
+				vendorID := name + "-vendor"
+				model.components = append(model.components, &component{
+					id:      vendorID,
+					name:    vendorID,
+					comment: "vendored third-party code bundled under vendor/",
+				})
+				model.relationships = append(model.relationships, &relationship{
+					from: root.id,
+					to:   vendorID,
+					kind: "CONTAINS",
+				})
+			}
+		}
+
+		licenses, err := scanLicenses(r.FS, coverageThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("scanning licenses for %s: %v", name, err)
+		}
+		root.licenseDeclared = licenses.declared
+		root.licenseConcluded = licenses.concluded
+		for _, sub := range licenses.subtrees {
+			subID := sanitizeComponentID(fmt.Sprintf("%s-%s", name, sub.path))
+			model.components = append(model.components, &component{
+				id:               subID,
+				name:             filepath.Base(sub.path),
+				licenseDeclared:  sub.license,
+				licenseConcluded: sub.license,
+				comment:          fmt.Sprintf("nested license tree at %s", sub.path),
+			})
+			model.relationships = append(model.relationships, &relationship{
+				from: root.id,
+				to:   subID,
+				kind: "CONTAINS",
+			})
+		}
+	}
+	return model, nil
+}
+
+// hasVendorDir reports whether fsys has a top-level "vendor" directory.
+func hasVendorDir(fsys fs.FS) bool {
+	info, err := fs.Stat(fsys, "vendor")
+	return err == nil && info.IsDir()
+}
+
+// componentIDUnsafe matches runs of characters not allowed in an SPDX
+// element ref (letters, digits, '.', '-'), so that ecosystem/name/version
+// tuples containing e.g. a scoped npm package's "/" and "@" or a Maven
+// "groupId:artifactId" don't produce an invalid SPDXID downstream.
+var componentIDUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// sanitizeComponentID strips anything not allowed in an SPDX element ref
+// out of raw, replacing each run with a single '-'.
+func sanitizeComponentID(raw string) string {
+	return componentIDUnsafe.ReplaceAllString(raw, "-")
+}
+
+// dependencyComponentID builds the document-local id for a manifest
+// dependency, sanitized for safe reuse as an SPDX element ref.
+func dependencyComponentID(d manifest.Dependency) string {
+	return sanitizeComponentID(fmt.Sprintf("%s-%s-%s", d.Ecosystem, d.Name, d.Version))
+}
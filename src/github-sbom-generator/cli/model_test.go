@@ -0,0 +1,111 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/lf-edge/eve/tools/github-sbom-generator/fetch"
+	"github.com/lf-edge/eve/tools/github-sbom-generator/manifest"
+)
+
+func TestBuildModelSanitizesNestedLicenseSubtreeID(t *testing.T) {
+	fsys := fstest.MapFS{
+		"LICENSE":                                {Data: []byte("MIT License\n")},
+		"third_party/github.com/foo/bar/LICENSE": {Data: []byte("Apache License\n")},
+	}
+	repo := &repoWithReader{
+		source: &fetch.Source{Getter: "file", VCS: fetch.VCSFile, Path: "acme/widget", URL: "/repo"},
+		FS:     fsys,
+	}
+
+	model, err := buildModel([]*repoWithReader{repo}, false, 0)
+	if err != nil {
+		t.Fatalf("buildModel: unexpected error: %v", err)
+	}
+
+	var found bool
+	for _, c := range model.components {
+		if c.comment == "" || !strings.Contains(c.comment, "third_party/github.com/foo/bar") {
+			continue
+		}
+		found = true
+		if strings.ContainsAny(c.id, "/") {
+			t.Errorf("nested license subtree component id %q contains an unsanitized path separator", c.id)
+		}
+	}
+	if !found {
+		t.Fatal("buildModel: expected a component for the nested third_party/github.com/foo/bar license subtree")
+	}
+}
+
+func TestBuildModelMultiRepoWithTransitive(t *testing.T) {
+	lock := `{
+		"packages": {
+			"": {},
+			"node_modules/lodash": {"version": "4.17.21"}
+		}
+	}`
+	widget := &repoWithReader{
+		source: &fetch.Source{Getter: "file", VCS: fetch.VCSFile, Path: "acme/widget", URL: "/widget"},
+		FS: fstest.MapFS{
+			"LICENSE":            {Data: []byte("MIT License\n")},
+			"package-lock.json":  {Data: []byte(lock)},
+			"vendor/modules.txt": {Data: []byte("# vendored\n")},
+		},
+	}
+	gadget := &repoWithReader{
+		source: &fetch.Source{Getter: "file", VCS: fetch.VCSFile, Path: "acme/gadget", URL: "/gadget"},
+		FS: fstest.MapFS{
+			"LICENSE": {Data: []byte("Apache License\n")},
+		},
+	}
+
+	model, err := buildModel([]*repoWithReader{widget, gadget}, true, 0)
+	if err != nil {
+		t.Fatalf("buildModel: unexpected error: %v", err)
+	}
+
+	byID := make(map[string]*component, len(model.components))
+	for _, c := range model.components {
+		byID[c.id] = c
+	}
+	if _, ok := byID["widget"]; !ok {
+		t.Fatalf("buildModel: missing root component for widget: %+v", model.components)
+	}
+	if _, ok := byID["gadget"]; !ok {
+		t.Fatalf("buildModel: missing root component for gadget: %+v", model.components)
+	}
+
+	wantDepID := dependencyComponentID(manifest.Dependency{Ecosystem: "npm", Name: "lodash", Version: "4.17.21"})
+	if _, ok := byID[wantDepID]; !ok {
+		t.Fatalf("buildModel: missing transitive npm dependency component %q: %+v", wantDepID, model.components)
+	}
+	if _, ok := byID["widget-vendor"]; !ok {
+		t.Fatalf("buildModel: missing vendor placeholder component for widget: %+v", model.components)
+	}
+
+	var hasDependsOn, hasVendorContains bool
+	for _, rel := range model.relationships {
+		if rel.from == "widget" && rel.to == wantDepID && rel.kind == "DEPENDS_ON" {
+			hasDependsOn = true
+		}
+		if rel.from == "widget" && rel.to == "widget-vendor" && rel.kind == "CONTAINS" {
+			hasVendorContains = true
+		}
+		// gadget never had a manifest or vendor/ dir, so it must not pick up
+		// any relationship meant for widget.
+		if rel.from == "gadget" && (rel.to == wantDepID || rel.to == "widget-vendor") {
+			t.Errorf("buildModel: gadget unexpectedly inherited widget's relationship %+v", rel)
+		}
+	}
+	if !hasDependsOn {
+		t.Errorf("buildModel: expected a DEPENDS_ON relationship from widget to %q", wantDepID)
+	}
+	if !hasVendorContains {
+		t.Error("buildModel: expected a CONTAINS relationship from widget to widget-vendor")
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lf-edge/eve/tools/github-sbom-generator/fetch"
+)
+
+// parseAll runs parse over repos with at most concurrency goroutines in
+// flight at once, preserving the input order in the returned slice.
+func parseAll(repos []string, concurrency int, creds *fetch.Credentials, cache *fetch.Cache) ([]*repoWithReader, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]*repoWithReader, len(repos))
+	errs := make([]error, len(repos))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		i, repo := i, repo
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			log.Debugf("Processing %s", repo)
+			r, err := parse(repo, creds, cache)
+			results[i] = r
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("error generating %s: %v", repos[i], err)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	spdxcommon "github.com/spdx/tools-golang/spdx/v2/common"
+	spdx "github.com/spdx/tools-golang/spdx/v2/v2_3"
+)
+
+// buildSbom renders model as an SPDX 2.3 document.
+func buildSbom(model *sbomModel, namespace, creator string) *spdx.Document {
+	ids := make(map[string]spdxcommon.DocElementID, len(model.components))
+	var packages []*spdx.Package
+	for _, c := range model.components {
+		id := spdxcommon.MakeDocElementID("Package", c.id)
+		ids[c.id] = id
+		pkg := &spdx.Package{
+			PackageName:             c.name,
+			PackageVersion:          c.version,
+			PackageSPDXIdentifier:   id.ElementRefID,
+			PackageDownloadLocation: "NOASSERTION",
+			PackageLicenseConcluded: "NOASSERTION",
+			PackageLicenseDeclared:  "NONE",
+			PackageComment:          c.comment,
+		}
+		if c.downloadLocation != "" {
+			pkg.PackageDownloadLocation = c.downloadLocation
+		}
+		if c.purl != "" {
+			pkg.PackageExternalReferences = []*spdx.PackageExternalReference{
+				{Category: "PACKAGE-MANAGER", RefType: "purl", Locator: c.purl},
+			}
+		}
+		if c.licenseDeclared != "" {
+			pkg.PackageLicenseDeclared = c.licenseDeclared
+		}
+		if c.licenseConcluded != "" {
+			pkg.PackageLicenseConcluded = c.licenseConcluded
+		}
+		if c.releaseDate != "" {
+			pkg.PackageReleaseDate = c.releaseDate
+		}
+		if c.supplierName != "" {
+			identity := spdxcommon.Supplier{Supplier: supplierIdentity(c.supplierName, c.supplierEmail), SupplierType: "Person"}
+			pkg.PackageSupplier = &identity
+			pkg.PackageOriginator = &spdxcommon.Originator{Originator: identity.Supplier, OriginatorType: "Person"}
+		}
+		packages = append(packages, pkg)
+	}
+
+	var relationships []*spdx.Relationship
+	for _, rel := range model.relationships {
+		relationships = append(relationships, &spdx.Relationship{
+			RefA:         ids[rel.from],
+			RefB:         ids[rel.to],
+			Relationship: rel.kind,
+		})
+	}
+
+	return &spdx.Document{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXIdentifier:    "DOCUMENT",
+		DocumentName:      "github-repo",
+		DocumentNamespace: fmt.Sprintf("%s-%s", namespace, uuid.New()),
+
+		CreationInfo: &spdx.CreationInfo{
+			Created: time.Now().UTC().Format(time.RFC3339),
+			Creators: []spdxcommon.Creator{
+				{Creator: creator, CreatorType: "Tool"},
+			},
+		},
+		Packages:      packages,
+		Relationships: relationships,
+	}
+}
+
+// supplierIdentity formats a git identity the way SPDX's Person-type
+// Supplier/Originator fields expect: "Name (email)", or bare "Name" when no
+// email was captured (e.g. a forge API response that omitted it).
+func supplierIdentity(name, email string) string {
+	if email == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, email)
+}
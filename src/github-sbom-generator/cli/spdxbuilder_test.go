@@ -0,0 +1,61 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import "testing"
+
+func TestBuildSbomRendersComponentsAndRelationships(t *testing.T) {
+	model := &sbomModel{
+		components: []*component{
+			{id: "widget", name: "widget", version: "v1.2.3", purl: "pkg:github/acme/widget@v1.2.3", licenseDeclared: "MIT"},
+			{id: "npm-lodash-4.17.21", name: "lodash", version: "4.17.21", purl: "pkg:npm/lodash@4.17.21"},
+			{id: "widget-third_party-foo-bar", name: "bar", licenseDeclared: "Apache-2.0", licenseConcluded: "Apache-2.0", comment: "nested license tree at third_party/foo/bar"},
+		},
+		relationships: []*relationship{
+			{from: "widget", to: "npm-lodash-4.17.21", kind: "DEPENDS_ON"},
+			{from: "widget", to: "widget-third_party-foo-bar", kind: "CONTAINS"},
+		},
+	}
+
+	doc := buildSbom(model, "https://example.com/sbom", "test-tool")
+
+	if len(doc.Packages) != len(model.components) {
+		t.Fatalf("buildSbom: got %d packages, want %d", len(doc.Packages), len(model.components))
+	}
+	byName := make(map[string]string) // PackageName -> PackageSPDXIdentifier
+	for _, pkg := range doc.Packages {
+		byName[pkg.PackageName] = string(pkg.PackageSPDXIdentifier)
+	}
+	widgetID, ok := byName["widget"]
+	if !ok {
+		t.Fatal("buildSbom: missing widget package")
+	}
+	lodashID, ok := byName["lodash"]
+	if !ok {
+		t.Fatal("buildSbom: missing lodash package")
+	}
+	barID, ok := byName["bar"]
+	if !ok {
+		t.Fatal("buildSbom: missing bar package")
+	}
+
+	if len(doc.Relationships) != 2 {
+		t.Fatalf("buildSbom: got %d relationships, want 2: %+v", len(doc.Relationships), doc.Relationships)
+	}
+	var sawDependsOn, sawContains bool
+	for _, rel := range doc.Relationships {
+		switch {
+		case string(rel.RefA.ElementRefID) == widgetID && string(rel.RefB.ElementRefID) == lodashID && rel.Relationship == "DEPENDS_ON":
+			sawDependsOn = true
+		case string(rel.RefA.ElementRefID) == widgetID && string(rel.RefB.ElementRefID) == barID && rel.Relationship == "CONTAINS":
+			sawContains = true
+		}
+	}
+	if !sawDependsOn {
+		t.Error("buildSbom: missing widget DEPENDS_ON lodash relationship")
+	}
+	if !sawContains {
+		t.Error("buildSbom: missing widget CONTAINS bar relationship")
+	}
+}
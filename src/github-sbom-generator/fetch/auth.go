@@ -0,0 +1,215 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// Credentials holds the tokens and keys `generate` was given for accessing
+// private repositories, via flags or environment fallback.
+type Credentials struct {
+	GitHubToken            string
+	GitLabToken            string
+	BitbucketAuth          string // "user:app-password"
+	SSHKeyPath             string
+	UseNetrc               bool
+	UseGitCredentialHelper bool
+}
+
+// NewCredentialsFromEnv builds Credentials from flag values, falling back to
+// the conventional environment variables when a flag was left empty.
+func NewCredentialsFromEnv(githubToken, gitlabToken, bitbucketAuth, sshKey string, useNetrc, useGitCredentialHelper bool) *Credentials {
+	if githubToken == "" {
+		githubToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if gitlabToken == "" {
+		gitlabToken = os.Getenv("GITLAB_TOKEN")
+	}
+	return &Credentials{
+		GitHubToken:            githubToken,
+		GitLabToken:            gitlabToken,
+		BitbucketAuth:          bitbucketAuth,
+		SSHKeyPath:             sshKey,
+		UseNetrc:               useNetrc,
+		UseGitCredentialHelper: useGitCredentialHelper,
+	}
+}
+
+// tokenForHost returns the configured bearer token for host, if any.
+func (c *Credentials) tokenForHost(host string) string {
+	if c == nil {
+		return ""
+	}
+	switch {
+	case strings.Contains(host, "github"):
+		return c.GitHubToken
+	case strings.Contains(host, "gitlab"):
+		return c.GitLabToken
+	default:
+		return ""
+	}
+}
+
+// basicAuthForHost returns username/password basic auth for host, resolving
+// in order: --bitbucket-auth (bitbucket only), netrc (if enabled), and
+// finally `git credential` (if enabled).
+func (c *Credentials) basicAuthForHost(host string) (user, pass string, ok bool) {
+	if c == nil {
+		return "", "", false
+	}
+	if strings.Contains(host, "bitbucket") && c.BitbucketAuth != "" {
+		if u, p, found := strings.Cut(c.BitbucketAuth, ":"); found {
+			return u, p, true
+		}
+	}
+	for _, h := range credentialHelpers(c) {
+		if u, p, found, err := h.Resolve(host); err == nil && found {
+			return u, p, true
+		}
+	}
+	return "", "", false
+}
+
+// CredentialHelper resolves a username/password for host from an external
+// credential store when no flag or environment value was supplied.
+type CredentialHelper interface {
+	Resolve(host string) (user, pass string, ok bool, err error)
+}
+
+// credentialHelpers returns, in priority order, the helpers enabled by c.
+// gitCredentialHelper shells out to `git credential fill`, which can block
+// on an interactive prompt in a non-interactive/CI run and hands out
+// whatever credentials git config resolves for the host -- so, like
+// netrcHelper, it is opt-in rather than applied to every https:// fetch.
+func credentialHelpers(c *Credentials) []CredentialHelper {
+	var helpers []CredentialHelper
+	if c.UseNetrc {
+		helpers = append(helpers, &netrcHelper{})
+	}
+	if c.UseGitCredentialHelper {
+		helpers = append(helpers, &gitCredentialHelper{})
+	}
+	return helpers
+}
+
+// netrcHelper resolves credentials from ~/.netrc (or $NETRC).
+type netrcHelper struct{}
+
+func (h *netrcHelper) Resolve(host string) (string, string, bool, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false, err
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", false, nil
+	}
+	defer f.Close()
+
+	var (
+		machine, login, password string
+		matched                  bool
+	)
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case "machine":
+			if scanner.Scan() {
+				machine = scanner.Text()
+				matched = machine == host
+				login, password = "", ""
+			}
+		case "login":
+			if matched && scanner.Scan() {
+				login = scanner.Text()
+			}
+		case "password":
+			if matched && scanner.Scan() {
+				password = scanner.Text()
+			}
+		}
+		if matched && login != "" && password != "" {
+			return login, password, true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// gitCredentialHelper shells out to `git credential fill`, which in turn
+// consults whatever credential.helper the user has configured (including an
+// OS keyring, on hosts where one is set up as the git credential helper).
+type gitCredentialHelper struct{}
+
+func (h *gitCredentialHelper) Resolve(host string) (string, string, bool, error) {
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + host + "\n\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", false, nil
+	}
+	var user, pass string
+	for _, line := range strings.Split(string(out), "\n") {
+		k, v, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		switch k {
+		case "username":
+			user = v
+		case "password":
+			pass = v
+		}
+	}
+	if user == "" || pass == "" {
+		return "", "", false, nil
+	}
+	return user, pass, true, nil
+}
+
+// authForSource resolves the go-git transport.AuthMethod to use when cloning
+// s, or nil if no credentials apply.
+func authForSource(s *Source, creds *Credentials) (transport.AuthMethod, error) {
+	if creds == nil {
+		return nil, nil
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, nil
+	}
+	switch u.Scheme {
+	case "ssh", "git+ssh":
+		if creds.SSHKeyPath != "" {
+			return gitssh.NewPublicKeysFromFile("git", creds.SSHKeyPath, "")
+		}
+		if os.Getenv("SSH_AUTH_SOCK") != "" {
+			return gitssh.NewSSHAgentAuth("git")
+		}
+		return nil, nil
+	case "http", "https":
+		if token := creds.tokenForHost(u.Host); token != "" {
+			return &githttp.BasicAuth{Username: token, Password: token}, nil
+		}
+		if user, pass, ok := creds.basicAuthForHost(u.Host); ok {
+			return &githttp.BasicAuth{Username: user, Password: pass}, nil
+		}
+		return nil, nil
+	default:
+		return nil, nil
+	}
+}
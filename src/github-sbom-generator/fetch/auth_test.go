@@ -0,0 +1,41 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import "testing"
+
+func TestCredentialHelpersGating(t *testing.T) {
+	tests := []struct {
+		name     string
+		creds    *Credentials
+		wantKind []string
+	}{
+		{"nothing enabled", &Credentials{}, nil},
+		{"netrc only", &Credentials{UseNetrc: true}, []string{"netrc"}},
+		{"git credential helper only", &Credentials{UseGitCredentialHelper: true}, []string{"git"}},
+		{"both", &Credentials{UseNetrc: true, UseGitCredentialHelper: true}, []string{"netrc", "git"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers := credentialHelpers(tt.creds)
+			if len(helpers) != len(tt.wantKind) {
+				t.Fatalf("credentialHelpers returned %d helpers, want %d", len(helpers), len(tt.wantKind))
+			}
+			for i, want := range tt.wantKind {
+				switch helpers[i].(type) {
+				case *netrcHelper:
+					if want != "netrc" {
+						t.Errorf("helper %d = netrcHelper, want %s", i, want)
+					}
+				case *gitCredentialHelper:
+					if want != "git" {
+						t.Errorf("helper %d = gitCredentialHelper, want %s", i, want)
+					}
+				default:
+					t.Errorf("helper %d has unexpected type %T", i, helpers[i])
+				}
+			}
+		})
+	}
+}
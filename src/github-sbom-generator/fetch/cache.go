@@ -0,0 +1,162 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// Cache is a content-addressed, on-disk store of previously-fetched sources,
+// keyed by host+path+ref. A ref that is a commit SHA is immutable and never
+// revalidated; a ref that looks like a tag or branch is revalidated against
+// the archive endpoint's ETag before being reused.
+type Cache struct {
+	dir string
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewCache opens (creating if necessary) the on-disk cache rooted at
+// $XDG_CACHE_HOME/github-sbom-generator, falling back to os.UserCacheDir.
+func NewCache() (*Cache, error) {
+	root := os.Getenv("XDG_CACHE_HOME")
+	if root == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		root = dir
+	}
+	dir := filepath.Join(root, "github-sbom-generator")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir, locks: make(map[string]*sync.Mutex)}, nil
+}
+
+// commitSHARegex recognizes a ref that is (plausibly) an immutable commit
+// hash, as opposed to a mutable tag or branch name.
+var commitSHARegex = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+func (c *Cache) key(s *Source) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s/%s#%s", s.Host, s.Path, s.Ref)))
+	return hex.EncodeToString(h[:])
+}
+
+func (c *Cache) path(s *Source) string {
+	return filepath.Join(c.dir, c.key(s))
+}
+
+// Get returns a directory populated with s's content, fetching (or
+// revalidating) it if necessary, along with a release func the caller must
+// call when done reading from it. The directory is never removed by
+// release: it is the on-disk cache, reused by later calls and later runs.
+//
+// The fetch-and-rename below is held under a per-key lock so that two
+// concurrent Get calls for the same source (the same repo passed twice, or
+// just contention under --concurrency) can't race on the identical tmp path
+// or have one goroutine delete dir out from under another that's mid-read:
+// the second caller blocks until the first's fetch (or revalidation) has
+// fully landed. It then returns the cached copy without re-fetching when the
+// ref is a commit SHA or revalidates as unchanged (HTTP 304); otherwise it
+// fetches again.
+func (c *Cache) Get(s *Source, creds *Credentials) (dir string, release func(), err error) {
+	dir = c.path(s)
+	release = func() {}
+
+	lock := c.lockFor(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if commitSHARegex.MatchString(s.Ref) {
+		if _, err := os.Stat(dir); err == nil {
+			return dir, release, nil
+		}
+	} else if fresh, err := c.revalidate(s, dir); err == nil && fresh {
+		return dir, release, nil
+	}
+
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return "", release, err
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return "", release, err
+	}
+	if err := Get(tmp, s, creds); err != nil {
+		os.RemoveAll(tmp)
+		return "", release, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", release, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return "", release, err
+	}
+	c.writeETag(s, dir)
+	return dir, release, nil
+}
+
+// lockFor returns the mutex serializing fetches for dir, creating it on
+// first use. The map only grows, never shrinks: it is bounded by the number
+// of distinct sources a single `generate` invocation touches, and the Cache
+// itself lives only for that process's lifetime.
+func (c *Cache) lockFor(dir string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.locks[dir]
+	if !ok {
+		l = &sync.Mutex{}
+		c.locks[dir] = l
+	}
+	return l
+}
+
+func (c *Cache) etagPath(dir string) string {
+	return dir + ".etag"
+}
+
+func (c *Cache) writeETag(s *Source, dir string) {
+	res, err := http.Head(ArchiveURL(s))
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if etag := res.Header.Get("ETag"); etag != "" {
+		_ = os.WriteFile(c.etagPath(dir), []byte(etag), 0644)
+	}
+}
+
+// revalidate reports whether the cached copy of a mutable ref (tag/branch)
+// is still current, by conditionally requesting the archive endpoint with
+// If-None-Match. A 304 means the cache is fresh; anything else means it
+// needs to be re-fetched.
+func (c *Cache) revalidate(s *Source, dir string) (fresh bool, err error) {
+	if _, err := os.Stat(dir); err != nil {
+		return false, err
+	}
+	etag, err := os.ReadFile(c.etagPath(dir))
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest(http.MethodGet, ArchiveURL(s), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("If-None-Match", string(etag))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	return res.StatusCode == http.StatusNotModified, nil
+}
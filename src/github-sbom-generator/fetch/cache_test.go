@@ -0,0 +1,80 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	t.Helper()
+	return &Cache{dir: t.TempDir(), locks: make(map[string]*sync.Mutex)}
+}
+
+func TestCacheKeyIsStableAndHostPathRefSensitive(t *testing.T) {
+	c := newTestCache(t)
+	a := &Source{Host: "github.com", Path: "foo/bar", Ref: "v1"}
+	b := &Source{Host: "github.com", Path: "foo/bar", Ref: "v1"}
+	other := &Source{Host: "github.com", Path: "foo/bar", Ref: "v2"}
+	otherHost := &Source{Host: "gitlab.com", Path: "foo/bar", Ref: "v1"}
+	otherPath := &Source{Host: "github.com", Path: "foo/baz", Ref: "v1"}
+
+	if c.key(a) != c.key(b) {
+		t.Error("key: identical sources produced different keys")
+	}
+	if c.key(a) == c.key(other) {
+		t.Error("key: sources differing only by ref produced the same key")
+	}
+	if c.key(a) == c.key(otherHost) {
+		t.Error("key: sources differing only by host produced the same key")
+	}
+	if c.key(a) == c.key(otherPath) {
+		t.Error("key: sources differing only by path produced the same key")
+	}
+}
+
+// TestCacheGetConcurrentSameSource exercises many goroutines racing to fetch
+// the same source through the "file" getter (a no-op Get, so this is purely
+// about the cache's own fetch-and-rename bookkeeping). Run with -race: before
+// the per-key lock, concurrent calls stomped the shared tmp path.
+func TestCacheGetConcurrentSameSource(t *testing.T) {
+	c := newTestCache(t)
+	s := &Source{Getter: "file", VCS: VCSFile, Host: "", Path: "", Ref: "", URL: t.TempDir()}
+
+	const n = 16
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	dirs := make([]string, n)
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dir, release, err := c.Get(s, nil)
+			errs[i] = err
+			dirs[i] = dir
+			if release != nil {
+				release()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Get() call %d: unexpected error: %v", i, err)
+		}
+	}
+	want := dirs[0]
+	for i, d := range dirs {
+		if d != want {
+			t.Errorf("Get() call %d returned dir %q, want %q (all callers asked for the same source)", i, d, want)
+		}
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("cached dir %q does not exist after concurrent Get calls: %v", want, err)
+	}
+}
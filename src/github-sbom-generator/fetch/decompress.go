@@ -0,0 +1,167 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Decompressor unpacks an archive read from src onto dst.
+type Decompressor interface {
+	Decompress(dst string, src io.Reader) error
+}
+
+// Decompressors maps a recognized file extension to the Decompressor that
+// handles it. Extensions are matched longest-first by ExtensionFor so that
+// ".tar.gz" is preferred over a bare ".gz" entry.
+var Decompressors = map[string]Decompressor{
+	".tar.gz":  &tarGzDecompressor{},
+	".tgz":     &tarGzDecompressor{},
+	".tar.bz2": &tarBz2Decompressor{},
+	".zip":     &zipDecompressor{},
+	".tar":     &tarDecompressor{},
+}
+
+// ExtensionFor returns the longest Decompressors key that name ends with, or
+// "" if none match.
+func ExtensionFor(name string) string {
+	best := ""
+	for ext := range Decompressors {
+		if strings.HasSuffix(name, ext) && len(ext) > len(best) {
+			best = ext
+		}
+	}
+	return best
+}
+
+// DecompressorFor looks up the Decompressor registered for name's extension.
+func DecompressorFor(name string) (Decompressor, error) {
+	ext := ExtensionFor(name)
+	if ext == "" {
+		return nil, fmt.Errorf("no decompressor registered for %q", name)
+	}
+	return Decompressors[ext], nil
+}
+
+// safeJoin resolves name against dst and rejects the result if it escapes
+// dst, guarding tar/zip extraction against "../"-style path traversal
+// (zip-slip) entries from an untrusted archive.
+func safeJoin(dst, name string) (string, error) {
+	target := filepath.Join(dst, name)
+	rel, err := filepath.Rel(dst, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q escapes destination directory", name)
+	}
+	return target, nil
+}
+
+func untar(dst string, tr *tar.Reader) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := safeJoin(dst, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			outFile, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(outFile, tr); err != nil {
+				outFile.Close()
+				return err
+			}
+			outFile.Close()
+		}
+	}
+}
+
+type tarGzDecompressor struct{}
+
+func (d *tarGzDecompressor) Decompress(dst string, src io.Reader) error {
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	return untar(dst, tar.NewReader(gz))
+}
+
+type tarBz2Decompressor struct{}
+
+func (d *tarBz2Decompressor) Decompress(dst string, src io.Reader) error {
+	return untar(dst, tar.NewReader(bzip2.NewReader(src)))
+}
+
+type tarDecompressor struct{}
+
+func (d *tarDecompressor) Decompress(dst string, src io.Reader) error {
+	return untar(dst, tar.NewReader(src))
+}
+
+type zipDecompressor struct{}
+
+func (d *zipDecompressor) Decompress(dst string, src io.Reader) error {
+	buf, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(strings.NewReader(string(buf)), int64(len(buf)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		target, err := safeJoin(dst, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		outFile, err := os.Create(target)
+		if err != nil {
+			rc.Close()
+			return err
+		}
+		_, copyErr := io.Copy(outFile, rc)
+		rc.Close()
+		outFile.Close()
+		if copyErr != nil {
+			return copyErr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtensionFor(t *testing.T) {
+	tests := map[string]string{
+		"repo.tar.gz":  ".tar.gz",
+		"repo.tgz":     ".tgz",
+		"repo.tar.bz2": ".tar.bz2",
+		"repo.zip":     ".zip",
+		"repo.tar":     ".tar",
+		"repo.txt":     "",
+	}
+	for name, want := range tests {
+		if got := ExtensionFor(name); got != want {
+			t.Errorf("ExtensionFor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestZipDecompressorRejectsPathTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("../../etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("pwned")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "extract")
+	dec := &zipDecompressor{}
+	if err := dec.Decompress(dst, &buf); err == nil {
+		t.Error("Decompress with a zip-slip entry: expected error, got nil")
+	}
+}
+
+func TestSafeJoinRejectsEscape(t *testing.T) {
+	dst := t.TempDir()
+	if _, err := safeJoin(dst, "../outside"); err == nil {
+		t.Error("safeJoin with ../ entry: expected error, got nil")
+	}
+	if _, err := safeJoin(dst, "nested/ok.txt"); err != nil {
+		t.Errorf("safeJoin with a normal entry: unexpected error: %v", err)
+	}
+}
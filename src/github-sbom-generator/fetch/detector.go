@@ -0,0 +1,190 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Detector turns a short reference a user typed on the command line into a
+// resolved Source. Detect returns ok=false, rather than an error, when src is
+// simply not a reference this detector recognizes, so that Detect can try
+// the next one in the list.
+type Detector interface {
+	Detect(src string) (s *Source, ok bool, err error)
+}
+
+// Detectors is the ordered list of Detector implementations consulted by
+// Detect. Forges are tried before the generic git:: and file:// fallbacks so
+// that e.g. "github.com/foo/bar" resolves to the GitHub archive getter
+// instead of a full clone.
+var Detectors = []Detector{
+	&githubDetector{},
+	&gitlabDetector{},
+	&bitbucketDetector{},
+	&s3Detector{},
+	&genericGitDetector{},
+	&importPathDetector{},
+	&fileDetector{},
+}
+
+// Detect runs src through Detectors in order and returns the first match.
+func Detect(src string) (*Source, error) {
+	for _, d := range Detectors {
+		s, ok, err := d.Detect(src)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to detect source type for %q", src)
+}
+
+// splitRef splits "host/path#ref" or "host/path@ref" into path and ref, with
+// "#" taking precedence since it is what the existing URL-based flags use.
+// An "@" followed by a "/" is URL userinfo (e.g. "ssh://git@host/x.git"),
+// not a ref separator.
+func splitRef(s string) (path, ref string) {
+	if idx := strings.Index(s, "#"); idx >= 0 {
+		return s[:idx], s[idx+1:]
+	}
+	if idx := strings.LastIndex(s, "@"); idx >= 0 && !strings.Contains(s[idx+1:], "/") {
+		return s[:idx], s[idx+1:]
+	}
+	return s, ""
+}
+
+var (
+	githubRegex    = regexp.MustCompile(`^(?:https?://)?github\.com/([^/#@]+)/([^/#@]+?)(?:\.git)?$`)
+	gitlabRegex    = regexp.MustCompile(`^(?:https?://)?gitlab\.com/([^/#@]+)/([^/#@]+?)(?:\.git)?$`)
+	bitbucketRegex = regexp.MustCompile(`^(?:https?://)?bitbucket\.org/([^/#@]+)/([^/#@]+?)(?:\.git)?$`)
+)
+
+type githubDetector struct{}
+
+func (d *githubDetector) Detect(src string) (*Source, bool, error) {
+	body, ref := splitRef(src)
+	m := githubRegex.FindStringSubmatch(body)
+	if m == nil {
+		return nil, false, nil
+	}
+	return &Source{
+		Getter: "http",
+		VCS:    VCSGitHub,
+		Host:   "github.com",
+		Path:   fmt.Sprintf("%s/%s", m[1], m[2]),
+		Ref:    ref,
+		URL:    fmt.Sprintf("https://github.com/%s/%s", m[1], m[2]),
+	}, true, nil
+}
+
+type gitlabDetector struct{}
+
+func (d *gitlabDetector) Detect(src string) (*Source, bool, error) {
+	body, ref := splitRef(src)
+	m := gitlabRegex.FindStringSubmatch(body)
+	if m == nil {
+		return nil, false, nil
+	}
+	return &Source{
+		// GitLab sources are always cloned with the git getter; no
+		// GitLab archive endpoint is used.
+		Getter: "git",
+		VCS:    VCSGitLab,
+		Host:   "gitlab.com",
+		Path:   fmt.Sprintf("%s/%s", m[1], m[2]),
+		Ref:    ref,
+		URL:    fmt.Sprintf("https://gitlab.com/%s/%s.git", m[1], m[2]),
+	}, true, nil
+}
+
+type bitbucketDetector struct{}
+
+func (d *bitbucketDetector) Detect(src string) (*Source, bool, error) {
+	body, ref := splitRef(src)
+	m := bitbucketRegex.FindStringSubmatch(body)
+	if m == nil {
+		return nil, false, nil
+	}
+	return &Source{
+		Getter: "http",
+		VCS:    VCSBitbucket,
+		Host:   "bitbucket.org",
+		Path:   fmt.Sprintf("%s/%s", m[1], m[2]),
+		Ref:    ref,
+		URL:    fmt.Sprintf("https://bitbucket.org/%s/%s", m[1], m[2]),
+	}, true, nil
+}
+
+// s3Detector recognizes the go-getter "s3::" forced-scheme prefix (e.g.
+// "s3::https://s3.amazonaws.com/bucket/key.tar.gz"), the only way to reach
+// the registered S3Getter.
+type s3Detector struct{}
+
+func (d *s3Detector) Detect(src string) (*Source, bool, error) {
+	body, ref := splitRef(src)
+	rest := strings.TrimPrefix(body, "s3::")
+	if rest == body {
+		return nil, false, nil
+	}
+	return &Source{
+		Getter: "s3",
+		VCS:    VCSS3,
+		URL:    rest,
+		Ref:    ref,
+	}, true, nil
+}
+
+// genericGitDetector recognizes the go-getter "git::" forced-scheme prefix
+// (e.g. "git::ssh://git@example.com/foo/bar.git") for hosts we don't special
+// case, and bare "host.xz/path.git" references.
+type genericGitDetector struct{}
+
+func (d *genericGitDetector) Detect(src string) (*Source, bool, error) {
+	body, ref := splitRef(src)
+	if strings.HasPrefix(body, "git::") {
+		rest := strings.TrimPrefix(body, "git::")
+		return &Source{
+			Getter: "git",
+			VCS:    VCSGeneric,
+			URL:    rest,
+			Ref:    ref,
+		}, true, nil
+	}
+	if strings.HasSuffix(body, ".git") {
+		url := body
+		if !strings.Contains(url, "://") {
+			url = "https://" + url
+		}
+		return &Source{
+			Getter: "git",
+			VCS:    VCSGeneric,
+			URL:    url,
+			Ref:    ref,
+		}, true, nil
+	}
+	return nil, false, nil
+}
+
+// fileDetector recognizes "file://" URLs and absolute/relative filesystem
+// paths, mirroring the historical behavior of parse() in cli/generate.go.
+type fileDetector struct{}
+
+func (d *fileDetector) Detect(src string) (*Source, bool, error) {
+	path := src
+	if strings.HasPrefix(src, "file://") {
+		path = strings.TrimPrefix(src, "file://")
+	} else if !strings.HasPrefix(src, "/") && !strings.HasPrefix(src, ".") {
+		return nil, false, nil
+	}
+	return &Source{
+		Getter: "file",
+		VCS:    VCSFile,
+		URL:    path,
+	}, true, nil
+}
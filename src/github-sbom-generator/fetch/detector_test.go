@@ -0,0 +1,44 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		src        string
+		wantGetter string
+		wantVCS    VCS
+		wantURL    string
+		wantRef    string
+	}{
+		{"github.com/foo/bar", "http", VCSGitHub, "https://github.com/foo/bar", ""},
+		{"github.com/foo/bar#v1.2.3", "http", VCSGitHub, "https://github.com/foo/bar", "v1.2.3"},
+		{"https://github.com/foo/bar.git", "http", VCSGitHub, "https://github.com/foo/bar", ""},
+		{"gitlab.com/foo/bar@main", "git", VCSGitLab, "https://gitlab.com/foo/bar.git", "main"},
+		{"bitbucket.org/foo/bar", "http", VCSBitbucket, "https://bitbucket.org/foo/bar", ""},
+		{"git::ssh://git@example.com/foo/bar.git#abc123", "git", VCSGeneric, "ssh://git@example.com/foo/bar.git", "abc123"},
+		{"s3::https://s3.amazonaws.com/bucket/key.tar.gz", "s3", VCSS3, "https://s3.amazonaws.com/bucket/key.tar.gz", ""},
+		{"example.com/foo/bar.git", "git", VCSGeneric, "https://example.com/foo/bar.git", ""},
+		{"./local/path", "file", VCSFile, "./local/path", ""},
+		{"file:///abs/path", "file", VCSFile, "/abs/path", ""},
+	}
+	for _, tt := range tests {
+		s, err := Detect(tt.src)
+		if err != nil {
+			t.Errorf("Detect(%q): unexpected error: %v", tt.src, err)
+			continue
+		}
+		if s.Getter != tt.wantGetter || s.VCS != tt.wantVCS || s.URL != tt.wantURL || s.Ref != tt.wantRef {
+			t.Errorf("Detect(%q) = %+v, want Getter=%s VCS=%s URL=%s Ref=%s",
+				tt.src, s, tt.wantGetter, tt.wantVCS, tt.wantURL, tt.wantRef)
+		}
+	}
+}
+
+func TestDetectUnrecognized(t *testing.T) {
+	if _, err := Detect("not a source at all"); err == nil {
+		t.Error("Detect(garbage): expected error, got nil")
+	}
+}
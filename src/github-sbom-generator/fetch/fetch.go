@@ -0,0 +1,54 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package fetch implements a pluggable source-fetching subsystem modeled on
+// HashiCorp go-getter: a Detector turns a short reference ("github.com/foo/bar@v1")
+// into a canonical, scheme-qualified URL, a Getter fetches the bytes for a
+// canonical URL, and a Decompressor unpacks the resulting archive onto disk.
+package fetch
+
+import "fmt"
+
+// VCS identifies the forge or protocol a Source was detected against. It
+// feeds both PackageDownloadLocation formatting and purl type selection.
+type VCS string
+
+// Known VCS/forge kinds. Generic is used when a source is git-backed but not
+// hosted on one of the forges we special-case.
+const (
+	VCSGitHub    VCS = "github"
+	VCSGitLab    VCS = "gitlab"
+	VCSBitbucket VCS = "bitbucket"
+	VCSGeneric   VCS = "generic"
+	VCSFile      VCS = "file"
+	VCSS3        VCS = "s3"
+)
+
+// Source is the canonical, fully-resolved form of whatever reference the
+// user passed to `generate`: a short reference, a URL, or a local path.
+type Source struct {
+	// Getter is the registered name of the Getter that can fetch this
+	// source, e.g. "http", "git", "file", "s3".
+	Getter string
+	// VCS identifies the forge, for purl/PackageDownloadLocation purposes.
+	VCS VCS
+	// Host is the forge hostname, e.g. "github.com". Empty for local files.
+	Host string
+	// Path is the repository path on the host, e.g. "foo/bar".
+	Path string
+	// Ref is the tag, branch, or commit the user asked for. May be empty.
+	Ref string
+	// URL is the canonical URL to hand to the Getter, forced-scheme prefix
+	// (e.g. "git::ssh://...") already stripped.
+	URL string
+}
+
+// String renders the source the way it would have been written as an
+// argument to `generate`, useful for log messages and PackageDownloadLocation
+// fallbacks.
+func (s *Source) String() string {
+	if s.Ref == "" {
+		return s.URL
+	}
+	return fmt.Sprintf("%s#%s", s.URL, s.Ref)
+}
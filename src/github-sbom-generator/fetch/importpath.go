@@ -0,0 +1,108 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// importPathDetector resolves Go-style import paths ("golang.org/x/crypto",
+// "k8s.io/api", "gopkg.in/yaml.v3") to their VCS root, the same way the `go`
+// tool does: a handful of well-known hosts are special-cased, and everything
+// else is resolved via the "?go-get=1" <meta name="go-import"> convention.
+//
+// It must run after the forge-specific and genericGitDetector entries in
+// Detectors, so that anything already recognized as a URL or a "host.xz/x.git"
+// reference is handled there instead.
+type importPathDetector struct{}
+
+// importPathRegex recognizes "host.tld/path..." with no scheme, no ".git"
+// suffix, and no "git::" prefix -- i.e. what's left once every other
+// Detector has declined.
+var importPathRegex = regexp.MustCompile(`^[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}(/[a-zA-Z0-9._~/-]+)+$`)
+
+func (d *importPathDetector) Detect(src string) (*Source, bool, error) {
+	body, ref := splitRef(src)
+	if !importPathRegex.MatchString(body) {
+		return nil, false, nil
+	}
+
+	if root, ok := wellKnownImportRoot(body); ok {
+		s, err := Detect(fmt.Sprintf("%s#%s", root, ref))
+		if err != nil {
+			return nil, false, err
+		}
+		return s, true, nil
+	}
+
+	repoRoot, err := discoverGoImportRoot(body)
+	if err != nil {
+		return nil, false, err
+	}
+	s, err := Detect(fmt.Sprintf("%s#%s", repoRoot, ref))
+	if err != nil {
+		return nil, false, err
+	}
+	return s, true, nil
+}
+
+// wellKnownImportRoot shortcuts the handful of hosts whose canonical repo
+// layout is common knowledge, avoiding a network round-trip for them.
+func wellKnownImportRoot(importPath string) (string, bool) {
+	// forge repos are always host/owner/repo; deeper segments are packages
+	for _, host := range []string{"github.com/", "gitlab.com/", "bitbucket.org/"} {
+		if rest := strings.TrimPrefix(importPath, host); rest != importPath {
+			parts := strings.SplitN(rest, "/", 3)
+			if len(parts) >= 2 && parts[0] != "" && parts[1] != "" {
+				return host + parts[0] + "/" + parts[1], true
+			}
+		}
+	}
+	if rest := strings.TrimPrefix(importPath, "golang.org/x/"); rest != importPath {
+		name, _, _ := strings.Cut(rest, "/")
+		return fmt.Sprintf("github.com/golang/%s", name), true
+	}
+	return "", false
+}
+
+// goImportMetaRegex matches <meta name="go-import" content="prefix vcs repo">.
+var goImportMetaRegex = regexp.MustCompile(`<meta\s+name=["']go-import["']\s+content=["']([^"']+)["']`)
+
+// discoverGoImportRoot performs the "?go-get=1" meta-tag lookup the `go` tool
+// uses for vanity import paths (e.g. k8s.io/api, gopkg.in/yaml.v3).
+func discoverGoImportRoot(importPath string) (string, error) {
+	res, err := http.Get(fmt.Sprintf("https://%s?go-get=1", importPath))
+	if err != nil {
+		return "", fmt.Errorf("resolving import path %s: %v", importPath, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("resolving import path %s: unexpected status %s", importPath, res.Status)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("resolving import path %s: %v", importPath, err)
+	}
+	body := string(raw)
+
+	m := goImportMetaRegex.FindStringSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("no go-import meta tag found for %s", importPath)
+	}
+	fields := strings.Fields(m[1])
+	if len(fields) != 3 {
+		return "", fmt.Errorf("malformed go-import meta tag for %s: %q", importPath, m[1])
+	}
+	prefix, vcs, repoRoot := fields[0], fields[1], fields[2]
+	if vcs != "git" {
+		return "", fmt.Errorf("import path %s resolves to unsupported vcs %q", importPath, vcs)
+	}
+	_ = prefix // the declared import prefix; repoRoot is what we actually fetch
+	return repoRoot, nil
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import "fmt"
+
+// PackageURL builds a purl for s, using the forge-specific purl type
+// (pkg:github, pkg:gitlab, pkg:bitbucket) when known, and falling back to
+// pkg:generic/git with a download_url qualifier otherwise.
+func (s *Source) PackageURL() string {
+	switch s.VCS {
+	case VCSGitHub:
+		return purlWithVersion(fmt.Sprintf("pkg:github/%s", s.Path), s.Ref)
+	case VCSGitLab:
+		return purlWithVersion(fmt.Sprintf("pkg:gitlab/%s", s.Path), s.Ref)
+	case VCSBitbucket:
+		return purlWithVersion(fmt.Sprintf("pkg:bitbucket/%s", s.Path), s.Ref)
+	default:
+		return fmt.Sprintf("pkg:generic/git?download_url=%s", s.String())
+	}
+}
+
+func purlWithVersion(base, ref string) string {
+	if ref == "" {
+		return base
+	}
+	return fmt.Sprintf("%s@%s", base, ref)
+}
+
+// DownloadLocation formats s the way SPDX's PackageDownloadLocation expects:
+// a VCS-prefixed locator for git-backed sources.
+func (s *Source) DownloadLocation() string {
+	switch s.VCS {
+	case VCSFile:
+		return "NOASSERTION"
+	case VCSS3:
+		return s.URL
+	default:
+		if s.Ref != "" {
+			return fmt.Sprintf("git+%s@%s", s.URL, s.Ref)
+		}
+		return fmt.Sprintf("git+%s", s.URL)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Release is the nearest-tag enrichment DescribeRelease resolves for a
+// Source: a git-describe-style version, plus the identity that cut the
+// release, for PackageVersion/PackageReleaseDate/PackageOriginator/
+// PackageSupplier. AuthorName/AuthorEmail come from the tag's tagger for an
+// annotated tag, or the commit's committer for a lightweight one.
+type Release struct {
+	Version     string
+	Date        time.Time
+	AuthorName  string
+	AuthorEmail string
+}
+
+// DescribeRelease resolves the nearest tag, annotated or lightweight alike,
+// reachable from s.Ref, git-describe style:
+// "vX.Y.Z+N-gSHA" when N commits have landed since the tag, or bare
+// "vX.Y.Z" on the tag itself. dir is the on-disk checkout cache.Get
+// produced. When it holds real git history -- the git Getter's fallback
+// clone, or a local checkout -- this walks it directly; when it doesn't
+// (the common case: an http-fetched archive has no .git), it falls back to
+// the forge's tags API. This enrichment is always best-effort: a nil
+// Release and nil error come back whenever no tag can be found, the forge
+// can't be queried this way, or the lookup fails (e.g. an unauthenticated
+// call hitting GitHub's rate limit) -- none of which should fail the SBOM
+// generation it would otherwise just be a detail of.
+func DescribeRelease(dir string, s *Source, creds *Credentials) (*Release, error) {
+	if repo, err := git.PlainOpen(dir); err == nil {
+		rel, err := describeFromRepo(repo)
+		if err != nil {
+			return nil, nil
+		}
+		return rel, nil
+	}
+	rel, err := describeFromForgeAPI(s, creds)
+	if err != nil {
+		return nil, nil
+	}
+	return rel, nil
+}
+
+// taggedCommit is one tag resolved to the commit it points at, with the
+// identity (tagger, or committer for a lightweight tag) describeFromRepo
+// reports for the nearest match.
+type taggedCommit struct {
+	name        string
+	authorName  string
+	authorEmail string
+	date        time.Time
+}
+
+func describeFromRepo(repo *git.Repository) (*Release, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	byCommit, err := tagsByCommit(repo)
+	if err != nil {
+		return nil, err
+	}
+	if len(byCommit) == 0 {
+		return nil, nil
+	}
+
+	commits, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		n     int
+		found *taggedCommit
+	)
+	err = commits.ForEach(func(c *object.Commit) error {
+		if t, ok := byCommit[c.Hash]; ok {
+			found = t
+			return storer.ErrStop
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, nil
+	}
+
+	version := found.name
+	if n > 0 {
+		version = fmt.Sprintf("%s+%d-g%s", found.name, n, head.Hash().String()[:7])
+	}
+	return &Release{
+		Version:     version,
+		Date:        found.date,
+		AuthorName:  found.authorName,
+		AuthorEmail: found.authorEmail,
+	}, nil
+}
+
+// tagsByCommit maps every tagged commit in repo to the nearest tag pointing
+// at it, resolving annotated tag objects down to the commit they wrap and
+// reading identity off the tagger; lightweight tags (a ref straight at a
+// commit) read identity off the commit's committer instead.
+func tagsByCommit(repo *git.Repository) (map[plumbing.Hash]*taggedCommit, error) {
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[plumbing.Hash]*taggedCommit)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := strings.TrimPrefix(ref.Name().String(), "refs/tags/")
+		if tagObj, err := repo.TagObject(ref.Hash()); err == nil {
+			commit, err := tagObj.Commit()
+			if err != nil {
+				return nil
+			}
+			out[commit.Hash] = &taggedCommit{
+				name:        name,
+				authorName:  tagObj.Tagger.Name,
+				authorEmail: tagObj.Tagger.Email,
+				date:        tagObj.Tagger.When,
+			}
+			return nil
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return nil
+		}
+		out[commit.Hash] = &taggedCommit{
+			name:        name,
+			authorName:  commit.Committer.Name,
+			authorEmail: commit.Committer.Email,
+			date:        commit.Committer.When,
+		}
+		return nil
+	})
+	return out, err
+}
+
+// githubTag is the subset of GitHub's "list tags" response DescribeRelease
+// needs; tags are returned newest-created first.
+type githubTag struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+type githubRef struct {
+	Object struct {
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	} `json:"object"`
+}
+
+type githubIdentity struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Date  string `json:"date"`
+}
+
+type githubTagObject struct {
+	Tagger githubIdentity `json:"tagger"`
+}
+
+type githubCommit struct {
+	Commit struct {
+		Committer githubIdentity `json:"committer"`
+	} `json:"commit"`
+}
+
+type githubCompare struct {
+	AheadBy  int `json:"ahead_by"`
+	BehindBy int `json:"behind_by"`
+}
+
+// describeFromForgeAPI approximates git-describe through GitHub's REST API,
+// for sources fetched as an archive rather than a clone. A ref that names
+// one of the listed tags is reported as that tag; otherwise only the first
+// listed tag is considered as a base, since "nearest reachable tag"
+// otherwise requires walking full history the API doesn't expose cheaply,
+// and a ref that isn't a descendant of that tag is left unenriched.
+func describeFromForgeAPI(s *Source, creds *Credentials) (*Release, error) {
+	if s.VCS != VCSGitHub {
+		return nil, nil
+	}
+	tags, err := githubListTags(s, creds)
+	if err != nil || len(tags) == 0 {
+		return nil, err
+	}
+
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	for _, t := range tags {
+		if t.Name == ref {
+			return githubTagRelease(s, creds, t.Name, 0, t.Commit.SHA)
+		}
+	}
+
+	nearest := tags[0]
+	cmp, err := githubCompareRefs(s, creds, nearest.Name, ref)
+	if err != nil || cmp.BehindBy > 0 {
+		// the ref isn't a descendant of the newest tag; without walking
+		// full history there's no cheap way to find one that is
+		return nil, nil
+	}
+	sha, err := githubResolveRef(s, creds, ref)
+	if err != nil {
+		sha = ref
+	}
+	return githubTagRelease(s, creds, nearest.Name, cmp.AheadBy, sha)
+}
+
+// githubTagRelease builds the Release for tagName, walking the tag ref down
+// to its tagger (annotated tag) or falling back to the commit's committer
+// (lightweight tag).
+func githubTagRelease(s *Source, creds *Credentials, tagName string, aheadBy int, sha string) (*Release, error) {
+	identity, when, err := githubTagIdentity(s, creds, tagName)
+	if err != nil {
+		return nil, err
+	}
+	version := tagName
+	if aheadBy > 0 {
+		short := sha
+		if len(short) > 7 {
+			short = short[:7]
+		}
+		version = fmt.Sprintf("%s+%d-g%s", tagName, aheadBy, short)
+	}
+	return &Release{
+		Version:     version,
+		Date:        when,
+		AuthorName:  identity.Name,
+		AuthorEmail: identity.Email,
+	}, nil
+}
+
+func githubTagIdentity(s *Source, creds *Credentials, tagName string) (githubIdentity, time.Time, error) {
+	var ref githubRef
+	if err := githubGet(fmt.Sprintf("https://api.github.com/repos/%s/git/ref/tags/%s", s.Path, tagName), creds, &ref); err != nil {
+		return githubIdentity{}, time.Time{}, err
+	}
+
+	if ref.Object.Type == "tag" {
+		var tagObj githubTagObject
+		if err := githubGet(fmt.Sprintf("https://api.github.com/repos/%s/git/tags/%s", s.Path, ref.Object.SHA), creds, &tagObj); err != nil {
+			return githubIdentity{}, time.Time{}, err
+		}
+		when, _ := time.Parse(time.RFC3339, tagObj.Tagger.Date)
+		return tagObj.Tagger, when, nil
+	}
+
+	var commit githubCommit
+	if err := githubGet(fmt.Sprintf("https://api.github.com/repos/%s/commits/%s", s.Path, ref.Object.SHA), creds, &commit); err != nil {
+		return githubIdentity{}, time.Time{}, err
+	}
+	when, _ := time.Parse(time.RFC3339, commit.Commit.Committer.Date)
+	return commit.Commit.Committer, when, nil
+}
+
+func githubListTags(s *Source, creds *Credentials) ([]githubTag, error) {
+	var tags []githubTag
+	if err := githubGet(fmt.Sprintf("https://api.github.com/repos/%s/tags", s.Path), creds, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func githubCompareRefs(s *Source, creds *Credentials, base, head string) (githubCompare, error) {
+	var cmp githubCompare
+	err := githubGet(fmt.Sprintf("https://api.github.com/repos/%s/compare/%s...%s", s.Path, base, head), creds, &cmp)
+	return cmp, err
+}
+
+func githubResolveRef(s *Source, creds *Credentials, ref string) (string, error) {
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := githubGet(fmt.Sprintf("https://api.github.com/repos/%s/commits/%s", s.Path, ref), creds, &commit); err != nil {
+		return "", err
+	}
+	return commit.SHA, nil
+}
+
+// githubGet issues an authenticated GET against the GitHub REST API and
+// decodes the JSON response into out.
+func githubGet(url string, creds *Credentials, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := creds.tokenForHost("github.com"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", url, res.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// cargoParser reads Cargo.lock's [[package]] tables. Cargo.lock's TOML is
+// simple enough (flat string/array values, no nesting beyond the table
+// headers) that a line scanner avoids pulling in a TOML dependency for one
+// file format.
+type cargoParser struct{}
+
+func (p *cargoParser) Detect(fsys fs.FS) bool {
+	_, err := fs.Stat(fsys, "Cargo.lock")
+	return err == nil
+}
+
+func (p *cargoParser) Parse(fsys fs.FS) ([]Dependency, error) {
+	f, err := fsys.Open("Cargo.lock")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		deps          []Dependency
+		inPackage     bool
+		name, version string
+	)
+	flush := func() {
+		if name != "" && version != "" {
+			deps = append(deps, Dependency{
+				Ecosystem: "cargo",
+				Name:      name,
+				Version:   version,
+				PURL:      fmt.Sprintf("pkg:cargo/%s@%s", name, version),
+			})
+		}
+		name, version = "", ""
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "[[package]]":
+			if inPackage {
+				flush()
+			}
+			inPackage = true
+		case strings.HasPrefix(line, "["):
+			if inPackage {
+				flush()
+			}
+			inPackage = false
+		case inPackage && strings.HasPrefix(line, "name ="):
+			name = cargoTOMLString(line)
+		case inPackage && strings.HasPrefix(line, "version ="):
+			version = cargoTOMLString(line)
+		}
+	}
+	if inPackage {
+		flush()
+	}
+	return deps, scanner.Err()
+}
+
+func cargoTOMLString(line string) string {
+	_, v, found := strings.Cut(line, "=")
+	if !found {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(v), `"`)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// goParser reads the require directives of a go.mod file. go.sum is not
+// consulted directly: go.mod's require versions are already the resolved,
+// pinned versions the build uses.
+type goParser struct{}
+
+func (p *goParser) Detect(fsys fs.FS) bool {
+	_, err := fs.Stat(fsys, "go.mod")
+	return err == nil
+}
+
+func (p *goParser) Parse(fsys fs.FS) ([]Dependency, error) {
+	f, err := fsys.Open("go.mod")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		deps      []Dependency
+		inRequire bool
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		switch {
+		case line == "require (":
+			inRequire = true
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case inRequire:
+			if dep, ok := parseRequireLine(line); ok {
+				deps = append(deps, dep)
+			}
+		case strings.HasPrefix(line, "require "):
+			if dep, ok := parseRequireLine(strings.TrimPrefix(line, "require ")); ok {
+				deps = append(deps, dep)
+			}
+		}
+	}
+	return deps, scanner.Err()
+}
+
+func parseRequireLine(line string) (Dependency, bool) {
+	// strip a trailing "// indirect" comment
+	if idx := strings.Index(line, "//"); idx >= 0 {
+		line = line[:idx]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Dependency{}, false
+	}
+	name, version := fields[0], fields[1]
+	return Dependency{
+		Ecosystem: "go",
+		Name:      name,
+		Version:   version,
+		PURL:      fmt.Sprintf("pkg:golang/%s@%s", name, version),
+	}, true
+}
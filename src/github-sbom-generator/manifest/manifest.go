@@ -0,0 +1,56 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package manifest parses the dependency manifests common to each language
+// ecosystem into a flat, pinned-version dependency list, so that `generate`
+// can emit one SPDX package and DEPENDS_ON relationship per transitive
+// dependency instead of just the root repository.
+package manifest
+
+import "io/fs"
+
+// Dependency is one resolved, pinned dependency found in a manifest.
+type Dependency struct {
+	// Ecosystem is a short label such as "go", "npm", "cargo", "pypi", "maven".
+	Ecosystem string
+	Name      string
+	Version   string
+	// PURL is the package URL identifying this dependency, e.g.
+	// "pkg:golang/golang.org/x/crypto@v0.14.0".
+	PURL string
+}
+
+// Parser recognizes and parses one ecosystem's manifest file(s).
+type Parser interface {
+	// Detect reports whether fsys contains this parser's manifest.
+	Detect(fsys fs.FS) bool
+	// Parse returns the pinned dependencies declared by the manifest.
+	Parse(fsys fs.FS) ([]Dependency, error)
+}
+
+// Parsers is the registry of ecosystem parsers consulted by ParseAll.
+var Parsers = []Parser{
+	&goParser{},
+	&npmParser{},
+	&cargoParser{},
+	&pipParser{},
+	&pipenvParser{},
+	&mavenParser{},
+}
+
+// ParseAll runs every registered Parser whose manifest is present in fsys
+// and returns the concatenation of their dependencies.
+func ParseAll(fsys fs.FS) ([]Dependency, error) {
+	var deps []Dependency
+	for _, p := range Parsers {
+		if !p.Detect(fsys) {
+			continue
+		}
+		d, err := p.Parse(fsys)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, d...)
+	}
+	return deps, nil
+}
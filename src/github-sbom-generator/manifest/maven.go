@@ -0,0 +1,63 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+)
+
+// mavenParser reads the <dependencies> declared directly in pom.xml. This
+// reflects the versions pom.xml pins explicitly; full transitive resolution
+// would require walking the effective POM, which needs network access to the
+// configured repositories and is out of scope here.
+type mavenParser struct{}
+
+func (p *mavenParser) Detect(fsys fs.FS) bool {
+	_, err := fs.Stat(fsys, "pom.xml")
+	return err == nil
+}
+
+type mavenPom struct {
+	Dependencies struct {
+		Dependency []mavenDependency `xml:"dependency"`
+	} `xml:"dependencies"`
+}
+
+type mavenDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+func (p *mavenParser) Parse(fsys fs.FS) ([]Dependency, error) {
+	f, err := fsys.Open("pom.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pom mavenPom
+	if err := xml.NewDecoder(f).Decode(&pom); err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	for _, d := range pom.Dependencies.Dependency {
+		if d.Version == "" {
+			// version managed elsewhere (e.g. a parent BOM); not resolvable
+			// without network access to the repository
+			continue
+		}
+		name := fmt.Sprintf("%s:%s", d.GroupID, d.ArtifactID)
+		deps = append(deps, Dependency{
+			Ecosystem: "maven",
+			Name:      name,
+			Version:   d.Version,
+			PURL:      fmt.Sprintf("pkg:maven/%s/%s@%s", d.GroupID, d.ArtifactID, d.Version),
+		})
+	}
+	return deps, nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// npmParser reads package-lock.json, which carries the resolved versions
+// package.json alone does not.
+type npmParser struct{}
+
+func (p *npmParser) Detect(fsys fs.FS) bool {
+	_, err := fs.Stat(fsys, "package-lock.json")
+	return err == nil
+}
+
+// npmLockPackage covers both the v1 "dependencies" shape and the v2/v3
+// "packages" shape; only the fields we need are declared.
+type npmLockPackage struct {
+	Version      string                     `json:"version"`
+	Dependencies map[string]*npmLockPackage `json:"dependencies"`
+}
+
+type npmLockFile struct {
+	Packages     map[string]*npmLockPackage `json:"packages"`
+	Dependencies map[string]*npmLockPackage `json:"dependencies"`
+}
+
+func (p *npmParser) Parse(fsys fs.FS) ([]Dependency, error) {
+	f, err := fsys.Open("package-lock.json")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lock npmLockFile
+	if err := json.NewDecoder(f).Decode(&lock); err != nil {
+		return nil, err
+	}
+
+	// lockfileVersion 2/3 (the npm 7+ default) populates both "packages" and
+	// "dependencies" for back-compat with older tooling; reading both would
+	// emit every dependency twice. Prefer "packages", the newer and more
+	// precise shape, and only fall back to "dependencies" for a v1 lockfile
+	// that lacks "packages" entirely.
+	var deps []Dependency
+	if len(lock.Packages) > 0 {
+		for path, pkg := range lock.Packages {
+			if path == "" || pkg == nil || pkg.Version == "" {
+				// "" is the root project entry itself
+				continue
+			}
+			deps = append(deps, npmDependency(npmPackageName(path), pkg.Version))
+		}
+	} else {
+		for name, pkg := range lock.Dependencies {
+			deps = append(deps, npmDependencyTree(name, pkg)...)
+		}
+	}
+	return deps, nil
+}
+
+// npmPackageName strips a "packages" key down to the installed package
+// name: a nested dependency (two versions of the same package needed at
+// different points in the tree) repeats "node_modules/" in the key, e.g.
+// "node_modules/foo/node_modules/bar", so only the segment after the *last*
+// occurrence is the actual package name.
+func npmPackageName(path string) string {
+	const marker = "node_modules/"
+	if idx := strings.LastIndex(path, marker); idx >= 0 {
+		return path[idx+len(marker):]
+	}
+	return path
+}
+
+func npmDependencyTree(name string, pkg *npmLockPackage) []Dependency {
+	if pkg == nil {
+		return nil
+	}
+	deps := []Dependency{npmDependency(name, pkg.Version)}
+	for childName, child := range pkg.Dependencies {
+		deps = append(deps, npmDependencyTree(childName, child)...)
+	}
+	return deps
+}
+
+func npmDependency(name, version string) Dependency {
+	return Dependency{
+		Ecosystem: "npm",
+		Name:      name,
+		Version:   version,
+		PURL:      fmt.Sprintf("pkg:npm/%s@%s", npmPurlName(name), version),
+	}
+}
+
+// npmPurlName percent-encodes a scoped package name's leading "@" per the
+// purl spec's npm type (e.g. "@babel/core" -> "%40babel/core"), so the scope
+// separator doesn't collide with the "@version" suffix purlWithVersion-style
+// callers append.
+func npmPurlName(name string) string {
+	if scope, rest, ok := strings.Cut(name, "/"); ok && strings.HasPrefix(scope, "@") {
+		return fmt.Sprintf("%%40%s/%s", strings.TrimPrefix(scope, "@"), rest)
+	}
+	return name
+}
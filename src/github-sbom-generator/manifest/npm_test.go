@@ -0,0 +1,113 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestNpmParserPrefersPackagesOverDependencies(t *testing.T) {
+	// A realistic lockfileVersion 3 file: both "packages" and "dependencies"
+	// describe the same two deps, as npm 7+ writes for back-compat.
+	lock := `{
+		"packages": {
+			"": {},
+			"node_modules/lodash": {"version": "4.17.21"},
+			"node_modules/@babel/core": {"version": "7.23.0"}
+		},
+		"dependencies": {
+			"lodash": {"version": "4.17.21"},
+			"@babel/core": {"version": "7.23.0"}
+		}
+	}`
+	fsys := fstest.MapFS{
+		"package-lock.json": {Data: []byte(lock)},
+	}
+
+	p := &npmParser{}
+	if !p.Detect(fsys) {
+		t.Fatal("Detect: expected true for a tree with package-lock.json")
+	}
+	deps, err := p.Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("Parse: got %d deps, want 2 (packages/dependencies overlap should not duplicate): %+v", len(deps), deps)
+	}
+
+	byName := map[string]Dependency{}
+	for _, d := range deps {
+		byName[d.Name] = d
+	}
+	scoped, ok := byName["@babel/core"]
+	if !ok {
+		t.Fatalf("Parse: missing @babel/core in %+v", deps)
+	}
+	if want := "pkg:npm/%40babel/core@7.23.0"; scoped.PURL != want {
+		t.Errorf("scoped package PURL = %q, want %q", scoped.PURL, want)
+	}
+}
+
+func TestNpmPackageNameStripsOnlyTheLastNodeModulesSegment(t *testing.T) {
+	tests := map[string]string{
+		"node_modules/lodash":                      "lodash",
+		"node_modules/foo/node_modules/bar":        "bar",
+		"node_modules/foo/node_modules/@scope/bar": "@scope/bar",
+		"node_modules/@scope/foo/node_modules/bar": "bar",
+	}
+	for path, want := range tests {
+		if got := npmPackageName(path); got != want {
+			t.Errorf("npmPackageName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestNpmParserNestedNodeModules(t *testing.T) {
+	// Two versions of "bar" are needed at different points in the tree, the
+	// common reason a v2/v3 lockfile nests "node_modules/" more than once.
+	lock := `{
+		"packages": {
+			"": {},
+			"node_modules/bar": {"version": "1.0.0"},
+			"node_modules/foo/node_modules/bar": {"version": "2.0.0"}
+		}
+	}`
+	fsys := fstest.MapFS{
+		"package-lock.json": {Data: []byte(lock)},
+	}
+
+	deps, err := (&npmParser{}).Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("Parse: got %d deps, want 2: %+v", len(deps), deps)
+	}
+	for _, d := range deps {
+		if d.Name != "bar" {
+			t.Errorf("Parse: dep name = %q, want \"bar\" (nested node_modules/ prefix not fully stripped)", d.Name)
+		}
+	}
+}
+
+func TestNpmParserV1FallsBackToDependencies(t *testing.T) {
+	lock := `{
+		"dependencies": {
+			"lodash": {"version": "4.17.21", "dependencies": {"isarray": {"version": "1.0.0"}}}
+		}
+	}`
+	fsys := fstest.MapFS{
+		"package-lock.json": {Data: []byte(lock)},
+	}
+
+	deps, err := (&npmParser{}).Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("Parse: got %d deps, want 2 (lodash + nested isarray): %+v", len(deps), deps)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestGoParser(t *testing.T) {
+	gomod := `module example.com/foo
+
+go 1.21
+
+require (
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/sys v0.15.0 // indirect
+)
+
+require github.com/pkg/errors v0.9.1
+`
+	fsys := fstest.MapFS{"go.mod": {Data: []byte(gomod)}}
+	p := &goParser{}
+	if !p.Detect(fsys) {
+		t.Fatal("Detect: expected true for a tree with go.mod")
+	}
+	deps, err := p.Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"github.com/stretchr/testify": "v1.8.4",
+		"golang.org/x/sys":            "v0.15.0",
+		"github.com/pkg/errors":       "v0.9.1",
+	}
+	if len(deps) != len(want) {
+		t.Fatalf("Parse: got %d deps, want %d: %+v", len(deps), len(want), deps)
+	}
+	for _, d := range deps {
+		if d.Ecosystem != "go" {
+			t.Errorf("dep %s: Ecosystem = %q, want \"go\"", d.Name, d.Ecosystem)
+		}
+		if want[d.Name] != d.Version {
+			t.Errorf("dep %s: Version = %q, want %q", d.Name, d.Version, want[d.Name])
+		}
+	}
+}
+
+func TestCargoParser(t *testing.T) {
+	lock := `# This file is automatically generated
+[[package]]
+name = "libc"
+version = "0.2.150"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "serde"
+version = "1.0.193"
+`
+	fsys := fstest.MapFS{"Cargo.lock": {Data: []byte(lock)}}
+	deps, err := (&cargoParser{}).Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("Parse: got %d deps, want 2: %+v", len(deps), deps)
+	}
+	if deps[0].Name != "libc" || deps[0].Version != "0.2.150" || deps[0].PURL != "pkg:cargo/libc@0.2.150" {
+		t.Errorf("deps[0] = %+v, want libc@0.2.150", deps[0])
+	}
+}
+
+func TestPipParser(t *testing.T) {
+	reqs := "requests==2.31.0\n# a comment\nunpinned-package\nflask==2.3.3  # pinned with trailing comment\n"
+	fsys := fstest.MapFS{"requirements.txt": {Data: []byte(reqs)}}
+	deps, err := (&pipParser{}).Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("Parse: got %d deps, want 2 (unpinned-package should be skipped): %+v", len(deps), deps)
+	}
+}
+
+func TestPipenvParser(t *testing.T) {
+	lock := `{
+		"default": {"requests": {"version": "==2.31.0"}},
+		"develop": {"pytest": {"version": "==7.4.3"}}
+	}`
+	fsys := fstest.MapFS{"Pipfile.lock": {Data: []byte(lock)}}
+	deps, err := (&pipenvParser{}).Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("Parse: got %d deps, want 2: %+v", len(deps), deps)
+	}
+}
+
+func TestMavenParser(t *testing.T) {
+	pom := `<project>
+  <dependencies>
+    <dependency>
+      <groupId>org.example</groupId>
+      <artifactId>foo</artifactId>
+      <version>1.2.3</version>
+    </dependency>
+    <dependency>
+      <groupId>org.example</groupId>
+      <artifactId>managed-elsewhere</artifactId>
+    </dependency>
+  </dependencies>
+</project>`
+	fsys := fstest.MapFS{"pom.xml": {Data: []byte(pom)}}
+	deps, err := (&mavenParser{}).Parse(fsys)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(deps) != 1 {
+		t.Fatalf("Parse: got %d deps, want 1 (version-less dependency skipped): %+v", len(deps), deps)
+	}
+	if deps[0].Name != "org.example:foo" || deps[0].PURL != "pkg:maven/org.example/foo@1.2.3" {
+		t.Errorf("deps[0] = %+v, want org.example:foo@1.2.3", deps[0])
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2023 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// pipParser reads a pip-compiled requirements.txt, i.e. one "name==version"
+// pin per line. Unpinned requirements (no "==") are skipped since there is
+// no resolved version to report.
+type pipParser struct{}
+
+func (p *pipParser) Detect(fsys fs.FS) bool {
+	_, err := fs.Stat(fsys, "requirements.txt")
+	return err == nil
+}
+
+func (p *pipParser) Parse(fsys fs.FS) ([]Dependency, error) {
+	f, err := fsys.Open("requirements.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var deps []Dependency
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		name, version, ok := strings.Cut(line, "==")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		version = strings.TrimSpace(version)
+		deps = append(deps, Dependency{
+			Ecosystem: "pypi",
+			Name:      name,
+			Version:   version,
+			PURL:      fmt.Sprintf("pkg:pypi/%s@%s", name, version),
+		})
+	}
+	return deps, scanner.Err()
+}
+
+// pipenvParser reads Pipfile.lock's "default" (and "develop") sections.
+type pipenvParser struct{}
+
+func (p *pipenvParser) Detect(fsys fs.FS) bool {
+	_, err := fs.Stat(fsys, "Pipfile.lock")
+	return err == nil
+}
+
+type pipenvPin struct {
+	Version string `json:"version"`
+}
+
+type pipenvLockFile struct {
+	Default map[string]pipenvPin `json:"default"`
+	Develop map[string]pipenvPin `json:"develop"`
+}
+
+func (p *pipenvParser) Parse(fsys fs.FS) ([]Dependency, error) {
+	f, err := fsys.Open("Pipfile.lock")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lock pipenvLockFile
+	if err := json.NewDecoder(f).Decode(&lock); err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	for _, section := range []map[string]pipenvPin{lock.Default, lock.Develop} {
+		for name, pin := range section {
+			version := strings.TrimPrefix(pin.Version, "==")
+			if version == "" {
+				continue
+			}
+			deps = append(deps, Dependency{
+				Ecosystem: "pypi",
+				Name:      name,
+				Version:   version,
+				PURL:      fmt.Sprintf("pkg:pypi/%s@%s", name, version),
+			})
+		}
+	}
+	return deps, nil
+}